@@ -1,13 +1,21 @@
 package writeas
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 )
 
-// LogIn authenticates a user with Write.as.
+// LogIn authenticates a user with Write.as, and sets the Client's token to the
+// user's new access token. To log in without changing the token of a Client
+// shared by other goroutines, call LogIn on a copy from WithToken("").
 // See https://developers.write.as/docs/api/#authenticate-a-user
 func (c *Client) LogIn(username, pass string) (*AuthUser, error) {
+	return c.LogInContext(context.Background(), username, pass)
+}
+
+// LogInContext is like LogIn, but uses ctx for the request.
+func (c *Client) LogInContext(ctx context.Context, username, pass string) (*AuthUser, error) {
 	u := &AuthUser{}
 	up := struct {
 		Alias string `json:"alias"`
@@ -17,7 +25,8 @@ func (c *Client) LogIn(username, pass string) (*AuthUser, error) {
 		Pass:  pass,
 	}
 
-	env, err := c.post("/auth/login", up, u)
+	endpoint := "/auth/login"
+	env, err := c.post(ctx, endpoint, up, u)
 	if err != nil {
 		return nil, err
 	}
@@ -30,15 +39,15 @@ func (c *Client) LogIn(username, pass string) (*AuthUser, error) {
 	status := env.Code
 	if status != http.StatusOK {
 		if status == http.StatusBadRequest {
-			return nil, fmt.Errorf("Bad request: %s", env.ErrorMessage)
+			return nil, newAPIError("POST", endpoint, env, fmt.Sprintf("Bad request: %s", env.ErrorMessage))
 		} else if status == http.StatusUnauthorized {
-			return nil, fmt.Errorf("Incorrect password.")
+			return nil, newAPIError("POST", endpoint, env, "Incorrect password.")
 		} else if status == http.StatusNotFound {
-			return nil, fmt.Errorf("User does not exist.")
+			return nil, newAPIError("POST", endpoint, env, "User does not exist.")
 		} else if status == http.StatusTooManyRequests {
-			return nil, fmt.Errorf("Too many log in attempts in a short period of time.")
+			return nil, newAPIError("POST", endpoint, env, "Too many log in attempts in a short period of time.")
 		}
-		return nil, fmt.Errorf("Problem authenticating: %d. %v\n", status, err)
+		return nil, newAPIError("POST", endpoint, env, fmt.Sprintf("Problem authenticating: %d. %s", status, env.ErrorMessage))
 	}
 
 	c.SetToken(u.AccessToken)
@@ -48,7 +57,14 @@ func (c *Client) LogIn(username, pass string) (*AuthUser, error) {
 // LogOut logs the current user out, making the Client's current access token
 // invalid.
 func (c *Client) LogOut() error {
-	env, err := c.delete("/auth/me", nil)
+	return c.LogOutContext(context.Background())
+}
+
+// LogOutContext is like LogOut, but uses ctx for the request.
+func (c *Client) LogOutContext(ctx context.Context) error {
+	token := c.Token()
+	endpoint := "/auth/me"
+	env, err := c.delete(ctx, endpoint, nil)
 	if err != nil {
 		return err
 	}
@@ -56,19 +72,24 @@ func (c *Client) LogOut() error {
 	status := env.Code
 	if status != http.StatusNoContent {
 		if status == http.StatusNotFound {
-			return fmt.Errorf("Access token is invalid or doesn't exist")
+			return newAPIError("DELETE", endpoint, env, "Access token is invalid or doesn't exist")
 		}
-		return fmt.Errorf("Unable to log out: %v", env.ErrorMessage)
+		return newAPIError("DELETE", endpoint, env, fmt.Sprintf("Unable to log out: %v", env.ErrorMessage))
 	}
 
-	// Logout successful, so update the Client
-	c.token = ""
+	// Logout successful, so update the Client, unless its token has been
+	// changed in the meantime
+	c.mu.Lock()
+	if c.token == token {
+		c.token = ""
+	}
+	c.mu.Unlock()
 
 	return nil
 }
 
 func (c *Client) isNotLoggedIn(code int) bool {
-	if c.token == "" {
+	if c.Token() == "" {
 		return false
 	}
 	return code == http.StatusUnauthorized
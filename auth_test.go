@@ -3,7 +3,9 @@ package writeas
 import "testing"
 
 func TestAuthentication(t *testing.T) {
-	dwac := NewDevClient()
+	srv := newTestServer()
+	defer srv.Close()
+	dwac := NewClientWith(Config{URL: srv.APIURL()})
 
 	// Log in
 	_, err := dwac.LogIn("demo", "demo")
@@ -1,6 +1,7 @@
 package writeas
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 )
@@ -28,12 +29,18 @@ type (
 
 // CreateContributor creates a new contributor on the given organization.
 func (c *Client) CreateContributor(sp *AuthorParams) (*Author, error) {
+	return c.CreateContributorContext(context.Background(), sp)
+}
+
+// CreateContributorContext is like CreateContributor, but uses ctx for the request.
+func (c *Client) CreateContributorContext(ctx context.Context, sp *AuthorParams) (*Author, error) {
 	if sp.OrgAlias == "" {
 		return nil, fmt.Errorf("AuthorParams.OrgAlias is required.")
 	}
 
 	a := &Author{}
-	env, err := c.post("/organizations/"+sp.OrgAlias+"/contributors", sp, a)
+	endpoint := "/organizations/" + sp.OrgAlias + "/contributors"
+	env, err := c.post(ctx, endpoint, sp, a)
 	if err != nil {
 		return nil, err
 	}
@@ -46,9 +53,9 @@ func (c *Client) CreateContributor(sp *AuthorParams) (*Author, error) {
 	status := env.Code
 	if status != http.StatusCreated {
 		if status == http.StatusBadRequest {
-			return nil, fmt.Errorf("Bad request: %s", env.ErrorMessage)
+			return nil, newAPIError("POST", endpoint, env, fmt.Sprintf("Bad request: %s", env.ErrorMessage))
 		}
-		return nil, fmt.Errorf("Problem creating author: %d. %s\n", status, env.ErrorMessage)
+		return nil, newAPIError("POST", endpoint, env, fmt.Sprintf("Problem creating author: %d. %s", status, env.ErrorMessage))
 	}
 	return a, nil
 }
@@ -1,9 +1,18 @@
 package writeas
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/writeas/go-writeas/v2/writeastest"
+)
 
 func TestClient_CreateContributor(t *testing.T) {
-	c := NewClientWith(Config{URL: "http://localhost:7777/api"})
+	srv := writeastest.NewServer()
+	defer srv.Close()
+	srv.AddUser("test", "test")
+	srv.AddOrganization("test", "write-as")
+
+	c := NewClientWith(Config{URL: srv.APIURL()})
 	_, err := c.LogIn("test", "test")
 	if err != nil {
 		t.Fatalf("login: %s", err)
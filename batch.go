@@ -0,0 +1,118 @@
+package writeas
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// defaultBatchConcurrency is the default number of posts CreatePosts creates
+// at once.
+const defaultBatchConcurrency = 4
+
+// ErrBatchStopped is the error reported for posts that CreatePosts didn't
+// attempt to create, because an earlier post failed with StopOnError set.
+var ErrBatchStopped = errors.New("not attempted; batch stopped after an earlier error")
+
+// BatchOptions configures how CreatePosts creates posts.
+type BatchOptions struct {
+	// Concurrency is the maximum number of posts created at once. Defaults
+	// to 4. Any RateLimiter set on the Client still applies to every
+	// request.
+	Concurrency int
+
+	// StopOnError stops creating posts as soon as one fails. Requests
+	// already in progress are allowed to finish, and posts that weren't
+	// attempted are reported with ErrBatchStopped.
+	StopOnError bool
+}
+
+// CreatePostResult is the outcome of creating a single post with
+// CreatePosts.
+type CreatePostResult struct {
+	// Post is the created post, or nil if creating it failed. It's set
+	// along with Err if the post was created but something failed
+	// afterwards, like storing its token.
+	Post *Post
+	Err  error
+}
+
+// CreatePosts publishes many posts at once, using a pool of concurrent
+// workers. It returns one result per post, in the same order as posts, along
+// with the first error that occurred, if any. Even when an error is
+// returned, every result is filled in, so callers can tell exactly which
+// posts were created.
+func (c *Client) CreatePosts(ctx context.Context, posts []*PostParams, opts *BatchOptions) ([]CreatePostResult, error) {
+	if opts == nil {
+		opts = &BatchOptions{}
+	}
+	workers := opts.Concurrency
+	if workers <= 0 {
+		workers = defaultBatchConcurrency
+	}
+	if workers > len(posts) {
+		workers = len(posts)
+	}
+
+	results := make([]CreatePostResult, len(posts))
+	jobs := make(chan int)
+
+	var mu sync.Mutex
+	var firstErr error
+	stopped := false
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		if opts.StopOnError {
+			stopped = true
+		}
+	}
+	isStopped := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return stopped
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if isStopped() {
+					results[i].Err = ErrBatchStopped
+					continue
+				}
+				if err := ctx.Err(); err != nil {
+					results[i].Err = err
+					fail(err)
+					continue
+				}
+
+				if posts[i] == nil {
+					err := errors.New("nil post")
+					results[i].Err = err
+					fail(err)
+					continue
+				}
+
+				p, err := c.CreatePostContext(ctx, posts[i])
+				results[i].Post = p
+				if err != nil {
+					results[i].Err = err
+					fail(err)
+				}
+			}
+		}()
+	}
+	for i := range posts {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results, firstErr
+}
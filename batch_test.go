@@ -0,0 +1,154 @@
+package writeas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestCreatePosts(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	c := NewClientWith(Config{URL: srv.APIURL()})
+
+	// Track the number of requests in flight at once
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	c.Use(func(next SendFunc) SendFunc {
+		return func(r *http.Request) (*http.Response, error) {
+			mu.Lock()
+			inFlight++
+			if inFlight > maxInFlight {
+				maxInFlight = inFlight
+			}
+			mu.Unlock()
+			defer func() {
+				mu.Lock()
+				inFlight--
+				mu.Unlock()
+			}()
+			return next(r)
+		}
+	})
+
+	posts := make([]*PostParams, 20)
+	for i := range posts {
+		posts[i] = &PostParams{Content: fmt.Sprintf("Post %d", i)}
+	}
+	// One post will fail
+	posts[7].Content = ""
+
+	res, err := c.CreatePosts(context.Background(), posts, &BatchOptions{Concurrency: 3})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected 400 error, got: %v", err)
+	}
+	if len(res) != len(posts) {
+		t.Fatalf("Got %d results, want %d", len(res), len(posts))
+	}
+	for i, r := range res {
+		if i == 7 {
+			if r.Err == nil || r.Post != nil {
+				t.Errorf("Expected post %d to fail, got: %+v", i, r)
+			}
+			continue
+		}
+		if r.Err != nil {
+			t.Errorf("Post %d failed: %v", i, r.Err)
+		} else if r.Post.Content != posts[i].Content {
+			t.Errorf("Result %d out of order: got %q, want %q", i, r.Post.Content, posts[i].Content)
+		}
+	}
+	if maxInFlight > 3 {
+		t.Errorf("Made %d requests at once, want at most 3", maxInFlight)
+	}
+}
+
+// failingTokenStore is a TokenStore that can't store anything.
+type failingTokenStore struct{}
+
+func (failingTokenStore) Get(id string) (string, error) { return "", nil }
+func (failingTokenStore) Put(id, token string) error    { return errors.New("disk full") }
+func (failingTokenStore) Delete(id string) error        { return nil }
+
+func TestCreatePostsTokenStoreFails(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	c := NewClientWith(Config{URL: srv.APIURL(), TokenStore: failingTokenStore{}})
+
+	res, err := c.CreatePosts(context.Background(), []*PostParams{{Content: "Created anyway"}}, nil)
+	if err == nil {
+		t.Fatalf("Expected error storing token")
+	}
+	if res[0].Err == nil || res[0].Post == nil || res[0].Post.Token == "" {
+		t.Errorf("Expected created post along with error, got: %+v", res[0])
+	}
+}
+
+func TestCreatePostsStopOnError(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	c := NewClientWith(Config{URL: srv.APIURL()})
+
+	posts := []*PostParams{
+		{Content: "First"},
+		{Content: ""},
+		{Content: "Third"},
+		{Content: "Fourth"},
+	}
+	res, err := c.CreatePosts(context.Background(), posts, &BatchOptions{Concurrency: 1, StopOnError: true})
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if res[0].Err != nil || res[0].Post == nil {
+		t.Errorf("First post should have been created: %+v", res[0])
+	}
+	if res[1].Err == nil {
+		t.Errorf("Second post should have failed")
+	}
+	for _, r := range res[2:] {
+		if r.Err != ErrBatchStopped {
+			t.Errorf("Expected ErrBatchStopped, got: %v", r.Err)
+		}
+	}
+}
+
+func TestCreatePostsNil(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	c := NewClientWith(Config{URL: srv.APIURL()})
+
+	res, err := c.CreatePosts(context.Background(), []*PostParams{{Content: "Hi"}, nil, {Content: "Hello"}}, nil)
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if res[1].Err == nil || res[1].Post != nil {
+		t.Errorf("Expected nil post to fail, got: %+v", res[1])
+	}
+	for _, i := range []int{0, 2} {
+		if res[i].Err != nil || res[i].Post == nil {
+			t.Errorf("Post %d should have been created: %+v", i, res[i])
+		}
+	}
+}
+
+func TestCreatePostsCanceled(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	c := NewClientWith(Config{URL: srv.APIURL()})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res, err := c.CreatePosts(ctx, []*PostParams{{Content: "Hi"}, {Content: "Hello"}}, nil)
+	if err != context.Canceled {
+		t.Fatalf("Expected context.Canceled, got: %v", err)
+	}
+	for _, r := range res {
+		if r.Err != context.Canceled {
+			t.Errorf("Expected context.Canceled, got: %v", r.Err)
+		}
+	}
+}
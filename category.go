@@ -1,8 +1,148 @@
 package writeas
 
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
 // Category represents a post tag with additional metadata, like a title and slug.
 type Category struct {
 	Hashtag string `json:"hashtag"`
 	Slug    string `json:"slug"`
 	Title   string `json:"title"`
 }
+
+var (
+	hashtagReg  = regexp.MustCompile(`(?:^|\s)#([\pL\pN_]*\pL[\pL\pN_]*)`)
+	codeSpanReg = regexp.MustCompile("(`+).*?(?:`+|$)")
+	urlReg      = regexp.MustCompile(`(?i)(?:\b[a-z][a-z0-9+.-]*://|\bwww\.)\S+`)
+	headingReg  = regexp.MustCompile(`^ {0,3}#{1,6}(?:\s|$)`)
+	fenceReg    = regexp.MustCompile("^ {0,3}(```+|~~~+)")
+)
+
+// NewCategory returns the Category for the given hashtag, with or without its
+// leading #. Its slug and title are made from the words in the hashtag, split
+// at underscores and changes in case, so #WritingTips becomes "writing-tips"
+// and "Writing Tips".
+func NewCategory(hashtag string) Category {
+	hashtag = strings.TrimPrefix(hashtag, "#")
+	words := hashtagWords(hashtag)
+	title := make([]string, len(words))
+	for i, w := range words {
+		r := []rune(w)
+		title[i] = string(unicode.ToUpper(r[0])) + string(r[1:])
+	}
+	return Category{
+		Hashtag: hashtag,
+		Slug:    strings.ToLower(strings.Join(words, "-")),
+		Title:   strings.Join(title, " "),
+	}
+}
+
+// hashtagWords splits a hashtag into words at underscores and where a
+// lowercase letter is followed by an uppercase one, or an uppercase letter
+// starts a new word after an acronym, as in "HTMLParser".
+func hashtagWords(tag string) []string {
+	var words []string
+	var cur []rune
+	r := []rune(tag)
+	for i, c := range r {
+		if c == '_' {
+			if len(cur) > 0 {
+				words = append(words, string(cur))
+			}
+			cur = nil
+			continue
+		}
+		if len(cur) > 0 && unicode.IsUpper(c) {
+			prev := cur[len(cur)-1]
+			nextLower := i+1 < len(r) && unicode.IsLower(r[i+1])
+			if unicode.IsLower(prev) || unicode.IsUpper(prev) && nextLower {
+				words = append(words, string(cur))
+				cur = nil
+			}
+		}
+		cur = append(cur, c)
+	}
+	if len(cur) > 0 {
+		words = append(words, string(cur))
+	}
+	return words
+}
+
+// ExtractHashtags returns the hashtags in the given post body, without their
+// leading #, in the order they first appear. Like on Write.as, a hashtag
+// follows a space or the start of a line, and is made up of letters, numbers
+// and underscores, including at least one letter. Hashtags in code, URLs and
+// headings are ignored, and ones that only differ by case are only returned
+// once.
+func ExtractHashtags(body string) []string {
+	var tags []string
+	seen := map[string]bool{}
+
+	fence := ""
+	prevBlank, prevCode := true, false
+	for _, line := range strings.Split(strings.Replace(body, "\r\n", "\n", -1), "\n") {
+		blank := strings.TrimSpace(line) == ""
+		code := false
+		if fence != "" {
+			if m := fenceReg.FindStringSubmatch(line); m != nil && m[1][0] == fence[0] && len(m[1]) >= len(fence) && strings.TrimSpace(line[len(m[0]):]) == "" {
+				fence = ""
+			}
+			continue
+		}
+		if m := fenceReg.FindStringSubmatch(line); m != nil {
+			fence = m[1]
+			continue
+		}
+		if !blank && (strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t")) && (prevBlank || prevCode) {
+			code = true
+		}
+		prevBlank, prevCode = blank, code || prevCode && blank
+		if code || headingReg.MatchString(line) {
+			continue
+		}
+
+		line = codeSpanReg.ReplaceAllString(line, " ")
+		line = urlReg.ReplaceAllString(line, " ")
+		for _, m := range hashtagReg.FindAllStringSubmatch(line, -1) {
+			slug := NewCategory(m[1]).Slug
+			if !seen[slug] {
+				seen[slug] = true
+				tags = append(tags, m[1])
+			}
+		}
+	}
+	return tags
+}
+
+// ExtractCategories returns a Category for each hashtag in the given post
+// body, as found by ExtractHashtags.
+func ExtractCategories(body string) []Category {
+	tags := ExtractHashtags(body)
+	if len(tags) == 0 {
+		return nil
+	}
+	cats := make([]Category, len(tags))
+	for i, t := range tags {
+		cats[i] = NewCategory(t)
+	}
+	return cats
+}
+
+// AddHashtagCategories adds a Category to sp.Categories for each hashtag in
+// sp.Content that isn't already there, so posts can be tagged without
+// waiting for the server to find their hashtags.
+func (sp *PostParams) AddHashtagCategories() {
+	have := map[string]bool{}
+	for _, c := range sp.Categories {
+		have[NewCategory(c.Hashtag).Slug] = true
+	}
+	for _, c := range ExtractCategories(sp.Content) {
+		if !have[c.Slug] {
+			have[c.Slug] = true
+			sp.Categories = append(sp.Categories, c)
+		}
+	}
+}
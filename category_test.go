@@ -0,0 +1,59 @@
+package writeas
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewCategory(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Category
+	}{
+		{"writing", Category{Hashtag: "writing", Slug: "writing", Title: "Writing"}},
+		{"#WritingTips", Category{Hashtag: "WritingTips", Slug: "writing-tips", Title: "Writing Tips"}},
+		{"go_lang", Category{Hashtag: "go_lang", Slug: "go-lang", Title: "Go Lang"}},
+		{"HTMLParser", Category{Hashtag: "HTMLParser", Slug: "html-parser", Title: "HTML Parser"}},
+		{"web3", Category{Hashtag: "web3", Slug: "web3", Title: "Web3"}},
+		{"écriture", Category{Hashtag: "écriture", Slug: "écriture", Title: "Écriture"}},
+	}
+	for _, test := range tests {
+		if got := NewCategory(test.in); got != test.want {
+			t.Errorf("NewCategory(%q) = %+v, want %+v", test.in, got, test.want)
+		}
+	}
+}
+
+func TestExtractHashtags(t *testing.T) {
+	body := "# A #heading\n" +
+		"Post about #writing and #go_lang, not #2020 or foo#bar. #Writing again.\n" +
+		"See https://example.com/#anchor and `#code` or www.example.com/ #日本語\n" +
+		"\n" +
+		"```\n" +
+		"#fenced\n" +
+		"```\n" +
+		"\n" +
+		"    #indented\n" +
+		"\n" +
+		"    #stillindented\n" +
+		"Last #one"
+	want := []string{"writing", "go_lang", "日本語", "one"}
+	if got := ExtractHashtags(body); !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v, want %v", got, want)
+	}
+}
+
+func TestAddHashtagCategories(t *testing.T) {
+	sp := &PostParams{
+		Content:    "About #Writing and #reading.",
+		Categories: []Category{{Hashtag: "writing"}},
+	}
+	sp.AddHashtagCategories()
+	want := []Category{
+		{Hashtag: "writing"},
+		{Hashtag: "reading", Slug: "reading", Title: "Reading"},
+	}
+	if !reflect.DeepEqual(sp.Categories, want) {
+		t.Errorf("Got %+v, want %+v", sp.Categories, want)
+	}
+}
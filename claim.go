@@ -0,0 +1,153 @@
+package writeas
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+)
+
+// claimChunkSize is the number of posts claimed in each request.
+const claimChunkSize = 50
+
+// TokenLister is a TokenStore that can list every token it holds, such as a
+// FileTokenStore.
+type TokenLister interface {
+	TokenStore
+	All() ([]OwnedPostParams, error)
+}
+
+// ClaimReport summarizes the outcome of claiming many anonymous posts at
+// once, sorting each post's ClaimPostResult by what happened to it.
+type ClaimReport struct {
+	// Claimed are the posts that now belong to the user.
+	Claimed []ClaimPostResult
+	// AlreadyOwned are the posts that already belonged to the user.
+	AlreadyOwned []ClaimPostResult
+	// NotFound are the posts that no longer exist.
+	NotFound []ClaimPostResult
+	// Failed are the posts that couldn't be claimed for any other reason,
+	// e.g. because their token was wrong. Each one's Err explains why.
+	Failed []ClaimPostResult
+}
+
+// Total returns the number of posts in the report.
+func (r *ClaimReport) Total() int {
+	return len(r.Claimed) + len(r.AlreadyOwned) + len(r.NotFound) + len(r.Failed)
+}
+
+func (r *ClaimReport) add(res ClaimPostResult) {
+	switch res.Code {
+	case http.StatusOK:
+		r.Claimed = append(r.Claimed, res)
+	case http.StatusConflict:
+		r.AlreadyOwned = append(r.AlreadyOwned, res)
+	case http.StatusNotFound:
+		r.NotFound = append(r.NotFound, res)
+	default:
+		r.Failed = append(r.Failed, res)
+	}
+}
+
+// ClaimStoredPosts claims every anonymous post in the given store for the
+// authenticated user, typically right after LogIn:
+//
+//	if _, err := c.LogIn(username, password); err != nil {
+//		// handle
+//	}
+//	report, err := c.ClaimStoredPosts(ctx, writeas.NewFileTokenStore("tokens.json"))
+//
+// Posts are claimed in chunks, and once each chunk is done, the posts that
+// now belong to the user (including those that already did) are removed
+// from the store. Posts that weren't found are kept, in case the Client is
+// pointed at the wrong server. If an error stops the claim partway, the
+// report covers the chunks that finished.
+func (c *Client) ClaimStoredPosts(ctx context.Context, s TokenLister) (*ClaimReport, error) {
+	posts, err := s.All()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read stored posts: %v", err)
+	}
+	return c.claimAll(ctx, posts, func(res ClaimPostResult) error {
+		if res.Code == http.StatusOK || res.Code == http.StatusConflict {
+			return s.Delete(res.ID)
+		}
+		return nil
+	})
+}
+
+// ClaimPostsFrom claims every anonymous post read from r for the
+// authenticated user, in chunks. The input is JSON, either a list of
+// OwnedPostParams, as written by FileTokenStore, or an object mapping post
+// IDs to tokens.
+func (c *Client) ClaimPostsFrom(ctx context.Context, r io.Reader) (*ClaimReport, error) {
+	posts, err := readOwnedPosts(r)
+	if err != nil {
+		return nil, err
+	}
+	return c.claimAll(ctx, posts, nil)
+}
+
+// claimAll claims posts in chunks, calling done with each result once its
+// chunk is finished.
+func (c *Client) claimAll(ctx context.Context, posts []OwnedPostParams, done func(ClaimPostResult) error) (*ClaimReport, error) {
+	report := &ClaimReport{}
+	for len(posts) > 0 {
+		n := claimChunkSize
+		if n > len(posts) {
+			n = len(posts)
+		}
+		chunk := posts[:n]
+		posts = posts[n:]
+
+		res, err := c.ClaimPostsContext(ctx, &chunk)
+		if err != nil {
+			return report, err
+		}
+		for i, r := range *res {
+			if r.ID == "" && i < len(chunk) {
+				r.ID = chunk[i].ID
+			}
+			report.add(r)
+			if done != nil {
+				if err := done(r); err != nil {
+					return report, fmt.Errorf("Unable to update stored posts: %v", err)
+				}
+			}
+		}
+	}
+	return report, nil
+}
+
+// readOwnedPosts reads post IDs and tokens from JSON that's either a list of
+// OwnedPostParams or an object mapping IDs to tokens.
+func readOwnedPosts(r io.Reader) ([]OwnedPostParams, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 {
+		return nil, nil
+	}
+
+	var posts []OwnedPostParams
+	if b[0] == '{' {
+		m := map[string]string{}
+		if err := json.Unmarshal(b, &m); err != nil {
+			return nil, fmt.Errorf("parse posts: %v", err)
+		}
+		for id, token := range m {
+			posts = append(posts, OwnedPostParams{ID: id, Token: token})
+		}
+		sort.Slice(posts, func(i, j int) bool { return posts[i].ID < posts[j].ID })
+		return posts, nil
+	}
+	if err := json.Unmarshal(b, &posts); err != nil {
+		return nil, fmt.Errorf("parse posts: %v", err)
+	}
+	return posts, nil
+}
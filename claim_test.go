@@ -0,0 +1,112 @@
+package writeas
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClaimStoredPosts(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	dir, err := ioutil.TempDir("", "writeas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s := NewFileTokenStore(filepath.Join(dir, "tokens.json"))
+
+	// Enough posts to need more than one chunk
+	for i := 0; i < claimChunkSize+5; i++ {
+		id, token := srv.AddPost("", fmt.Sprintf("Anonymous post %d.", i))
+		s.Put(id, token)
+	}
+	ownedID, ownedToken := srv.AddPost("", "Claimed earlier.")
+	s.Put(ownedID, ownedToken)
+	otherID, _ := srv.AddPost("", "Someone else's.")
+	s.Put(otherID, "wrong-token")
+	s.Put("doesnotexist", "token")
+
+	c := NewClientWith(Config{URL: srv.APIURL()})
+	if _, err := c.LogIn("demo", "demo"); err != nil {
+		t.Fatalf("Unable to log in: %v", err)
+	}
+	if _, err := c.ClaimPosts(&[]OwnedPostParams{{ID: ownedID, Token: ownedToken}}); err != nil {
+		t.Fatalf("Unable to claim post: %v", err)
+	}
+
+	report, err := c.ClaimStoredPosts(context.Background(), s)
+	if err != nil {
+		t.Fatalf("Claim failed: %v", err)
+	}
+	if len(report.Claimed) != claimChunkSize+5 {
+		t.Errorf("Claimed %d posts, want %d", len(report.Claimed), claimChunkSize+5)
+	}
+	if len(report.AlreadyOwned) != 1 || report.AlreadyOwned[0].ID != ownedID {
+		t.Errorf("AlreadyOwned = %+v, want %s", report.AlreadyOwned, ownedID)
+	}
+	if len(report.NotFound) != 1 || report.NotFound[0].ID != "doesnotexist" {
+		t.Errorf("NotFound = %+v, want doesnotexist", report.NotFound)
+	}
+	if len(report.Failed) != 1 || report.Failed[0].ID != otherID || report.Failed[0].Err() == nil {
+		t.Errorf("Failed = %+v, want %s", report.Failed, otherID)
+	}
+	if report.Total() != claimChunkSize+8 {
+		t.Errorf("Total = %d, want %d", report.Total(), claimChunkSize+8)
+	}
+
+	left, err := s.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 2 {
+		t.Errorf("Store has %d posts left, want 2 (not found and failed): %+v", len(left), left)
+	}
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		if _, err := c.WithToken("").ClaimStoredPosts(context.Background(), s); err == nil {
+			t.Errorf("Expected error claiming without logging in")
+		}
+		if left, _ := s.All(); len(left) != 2 {
+			t.Errorf("Store changed after failed claim: %+v", left)
+		}
+	})
+}
+
+func TestClaimPostsFrom(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	id1, token1 := srv.AddPost("", "First.")
+	id2, token2 := srv.AddPost("", "Second.")
+	c := NewClientWith(Config{URL: srv.APIURL()})
+	if _, err := c.LogIn("demo", "demo"); err != nil {
+		t.Fatalf("Unable to log in: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"list", fmt.Sprintf(`[{"id": %q, "token": %q}]`, id1, token1)},
+		{"map", fmt.Sprintf(`{%q: %q}`, id2, token2)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			report, err := c.ClaimPostsFrom(context.Background(), strings.NewReader(test.input))
+			if err != nil {
+				t.Fatalf("Claim failed: %v", err)
+			}
+			if len(report.Claimed) != 1 || report.Claimed[0].Post == nil {
+				t.Errorf("Expected one claimed post, got: %+v", report)
+			}
+		})
+	}
+
+	if _, err := c.ClaimPostsFrom(context.Background(), strings.NewReader("not json")); err == nil {
+		t.Errorf("Expected error for invalid input")
+	}
+}
@@ -1,6 +1,7 @@
 package writeas
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 )
@@ -19,6 +20,12 @@ type (
 		Email       string `json:"email,omitempty"`
 		URL         string `json:"url,omitempty"`
 
+		Script              string               `json:"script,omitempty"`
+		Format              CollectionFormat     `json:"format,omitempty"`
+		Visibility          CollectionVisibility `json:"visibility"`
+		MonetizationPointer string               `json:"monetization_pointer,omitempty"`
+		Font                string               `json:"font,omitempty"`
+
 		TotalPosts int `json:"total_posts"`
 
 		Posts *[]Post `json:"posts,omitempty"`
@@ -30,14 +37,74 @@ type (
 		Title       string `json:"title"`
 		Description string `json:"description,omitempty"`
 	}
+
+	// CollectionUpdateParams holds values for updating a collection's
+	// settings. Only non-nil fields are changed; setting a field to an empty
+	// string clears it.
+	CollectionUpdateParams struct {
+		Title       *string `json:"title,omitempty"`
+		Description *string `json:"description,omitempty"`
+
+		// Visibility controls who can see the collection. When setting it to
+		// VisibilityPassword, Password must also be given.
+		Visibility *CollectionVisibility `json:"visibility,omitempty"`
+		Password   string                `json:"password,omitempty"`
+
+		// StyleSheet is the collection's custom CSS, and Script its custom
+		// JavaScript.
+		StyleSheet *string `json:"style_sheet,omitempty"`
+		Script     *string `json:"script,omitempty"`
+
+		Format *CollectionFormat `json:"format,omitempty"`
+
+		// MonetizationPointer is a Web Monetization payment pointer, like
+		// $wallet.example.com/alice.
+		MonetizationPointer *string `json:"monetization_pointer,omitempty"`
+
+		// Font is the default font for new posts: "norm", "sans", "mono",
+		// "wrap" or "code".
+		Font *string `json:"font,omitempty"`
+	}
+)
+
+// CollectionVisibility determines who can see a collection.
+type CollectionVisibility int
+
+const (
+	// VisibilityUnlisted collections can be read by anyone with the link.
+	VisibilityUnlisted CollectionVisibility = 0
+	// VisibilityPublic collections are also listed in the public reader.
+	VisibilityPublic CollectionVisibility = 1
+	// VisibilityPrivate collections can only be read by their owner.
+	VisibilityPrivate CollectionVisibility = 2
+	// VisibilityPassword collections can be read by anyone with the password.
+	VisibilityPassword CollectionVisibility = 4
+)
+
+// CollectionFormat determines how a collection's posts are displayed.
+type CollectionFormat string
+
+const (
+	// FormatBlog shows posts newest first, with dates.
+	FormatBlog CollectionFormat = "blog"
+	// FormatNovel shows posts oldest first, without dates.
+	FormatNovel CollectionFormat = "novel"
+	// FormatNotebook shows posts newest first, without dates.
+	FormatNotebook CollectionFormat = "notebook"
 )
 
 // CreateCollection creates a new collection, returning a user-friendly error
 // if one comes up. Requires a Write.as subscription. See
 // https://developers.write.as/docs/api/#create-a-collection
 func (c *Client) CreateCollection(sp *CollectionParams) (*Collection, error) {
+	return c.CreateCollectionContext(context.Background(), sp)
+}
+
+// CreateCollectionContext is like CreateCollection, but uses ctx for the request.
+func (c *Client) CreateCollectionContext(ctx context.Context, sp *CollectionParams) (*Collection, error) {
 	p := &Collection{}
-	env, err := c.post("/collections", sp, p)
+	endpoint := "/collections"
+	env, err := c.post(ctx, endpoint, sp, p)
 	if err != nil {
 		return nil, err
 	}
@@ -50,15 +117,15 @@ func (c *Client) CreateCollection(sp *CollectionParams) (*Collection, error) {
 	status := env.Code
 	if status != http.StatusCreated {
 		if status == http.StatusBadRequest {
-			return nil, fmt.Errorf("Bad request: %s", env.ErrorMessage)
+			return nil, newAPIError("POST", endpoint, env, fmt.Sprintf("Bad request: %s", env.ErrorMessage))
 		} else if status == http.StatusForbidden {
-			return nil, fmt.Errorf("Casual or Pro user required.")
+			return nil, newAPIError("POST", endpoint, env, "Casual or Pro user required.")
 		} else if status == http.StatusConflict {
-			return nil, fmt.Errorf("Collection name is already taken.")
+			return nil, newAPIError("POST", endpoint, env, "Collection name is already taken.")
 		} else if status == http.StatusPreconditionFailed {
-			return nil, fmt.Errorf("Reached max collection quota.")
+			return nil, newAPIError("POST", endpoint, env, "Reached max collection quota.")
 		}
-		return nil, fmt.Errorf("Problem getting post: %d. %v\n", status, err)
+		return nil, newAPIError("POST", endpoint, env, fmt.Sprintf("Problem creating collection: %d. %s", status, env.ErrorMessage))
 	}
 	return p, nil
 }
@@ -67,8 +134,14 @@ func (c *Client) CreateCollection(sp *CollectionParams) (*Collection, error) {
 // (in user-friendly form) that occurs. See
 // https://developers.write.as/docs/api/#retrieve-a-collection
 func (c *Client) GetCollection(alias string) (*Collection, error) {
+	return c.GetCollectionContext(context.Background(), alias)
+}
+
+// GetCollectionContext is like GetCollection, but uses ctx for the request.
+func (c *Client) GetCollectionContext(ctx context.Context, alias string) (*Collection, error) {
 	coll := &Collection{}
-	env, err := c.get(fmt.Sprintf("/collections/%s", alias), coll)
+	endpoint := "/collections/" + alias
+	env, err := c.get(ctx, endpoint, coll)
 	if err != nil {
 		return nil, err
 	}
@@ -82,9 +155,9 @@ func (c *Client) GetCollection(alias string) (*Collection, error) {
 	if status == http.StatusOK {
 		return coll, nil
 	} else if status == http.StatusNotFound {
-		return nil, fmt.Errorf("Collection not found.")
+		return nil, newAPIError("GET", endpoint, env, "Collection not found.")
 	} else {
-		return nil, fmt.Errorf("Problem getting collection: %d. %v\n", status, err)
+		return nil, newAPIError("GET", endpoint, env, fmt.Sprintf("Problem getting collection: %d. %s", status, env.ErrorMessage))
 	}
 }
 
@@ -92,8 +165,44 @@ func (c *Client) GetCollection(alias string) (*Collection, error) {
 // and any error (in user-friendly form) that occurs. See
 // https://developers.write.as/docs/api/#retrieve-collection-posts
 func (c *Client) GetCollectionPosts(alias string) (*[]Post, error) {
+	return c.GetCollectionPostsContext(context.Background(), alias)
+}
+
+// GetCollectionPostsContext is like GetCollectionPosts, but uses ctx for the request.
+func (c *Client) GetCollectionPostsContext(ctx context.Context, alias string) (*[]Post, error) {
+	coll, err := c.getCollectionPosts(ctx, alias, 0)
+	if err != nil {
+		return nil, err
+	}
+	return coll.Posts, nil
+}
+
+// GetCollectionPostsPage retrieves one page of a collection's posts, starting
+// at page 1. The returned Collection's Posts contains only the posts on that
+// page, while its TotalPosts is the number of posts across all pages. Use
+// CollectionPosts to iterate through every page.
+func (c *Client) GetCollectionPostsPage(alias string, page int) (*Collection, error) {
+	return c.GetCollectionPostsPageContext(context.Background(), alias, page)
+}
+
+// GetCollectionPostsPageContext is like GetCollectionPostsPage, but uses ctx for the request.
+func (c *Client) GetCollectionPostsPageContext(ctx context.Context, alias string, page int) (*Collection, error) {
+	if page < 1 {
+		return nil, fmt.Errorf("Page must be 1 or greater.")
+	}
+	return c.getCollectionPosts(ctx, alias, page)
+}
+
+// getCollectionPosts retrieves the given page of a collection's posts, or
+// the server's default page if page is 0.
+func (c *Client) getCollectionPosts(ctx context.Context, alias string, page int) (*Collection, error) {
 	coll := &Collection{}
-	env, err := c.get(fmt.Sprintf("/collections/%s/posts", alias), coll)
+	endpoint := "/collections/" + alias + "/posts"
+	params := ""
+	if page > 0 {
+		params = fmt.Sprintf("?page=%d", page)
+	}
+	env, err := c.get(ctx, endpoint+params, coll)
 	if err != nil {
 		return nil, err
 	}
@@ -105,11 +214,14 @@ func (c *Client) GetCollectionPosts(alias string) (*[]Post, error) {
 	status := env.Code
 
 	if status == http.StatusOK {
-		return coll.Posts, nil
+		if coll.Posts == nil {
+			coll.Posts = &[]Post{}
+		}
+		return coll, nil
 	} else if status == http.StatusNotFound {
-		return nil, fmt.Errorf("Collection not found.")
+		return nil, newAPIError("GET", endpoint, env, "Collection not found.")
 	} else {
-		return nil, fmt.Errorf("Problem getting collection: %d. %v\n", status, err)
+		return nil, newAPIError("GET", endpoint, env, fmt.Sprintf("Problem getting collection: %d. %s", status, env.ErrorMessage))
 	}
 }
 
@@ -117,9 +229,15 @@ func (c *Client) GetCollectionPosts(alias string) (*[]Post, error) {
 // and any error (in user-friendly form) that occurs). See
 // https://developers.write.as/docs/api/#retrieve-a-collection-post
 func (c *Client) GetCollectionPost(alias, slug string) (*Post, error) {
+	return c.GetCollectionPostContext(context.Background(), alias, slug)
+}
+
+// GetCollectionPostContext is like GetCollectionPost, but uses ctx for the request.
+func (c *Client) GetCollectionPostContext(ctx context.Context, alias, slug string) (*Post, error) {
 	post := Post{}
 
-	env, err := c.get(fmt.Sprintf("/collections/%s/posts/%s", alias, slug), &post)
+	endpoint := fmt.Sprintf("/collections/%s/posts/%s", alias, slug)
+	env, err := c.get(ctx, endpoint, &post)
 	if err != nil {
 		return nil, err
 	}
@@ -131,17 +249,23 @@ func (c *Client) GetCollectionPost(alias, slug string) (*Post, error) {
 	if env.Code == http.StatusOK {
 		return &post, nil
 	} else if env.Code == http.StatusNotFound {
-		return nil, fmt.Errorf("Post %s not found in collection %s", slug, alias)
+		return nil, newAPIError("GET", endpoint, env, fmt.Sprintf("Post %s not found in collection %s", slug, alias))
 	}
 
-	return nil, fmt.Errorf("Problem getting post %s from collection %s: %d. %v\n", slug, alias, env.Code, err)
+	return nil, newAPIError("GET", endpoint, env, fmt.Sprintf("Problem getting post %s from collection %s: %d. %s", slug, alias, env.Code, env.ErrorMessage))
 }
 
 // GetUserCollections retrieves the authenticated user's collections.
 // See https://developers.write.as/docs/api/#retrieve-user-39-s-collections
 func (c *Client) GetUserCollections() (*[]Collection, error) {
+	return c.GetUserCollectionsContext(context.Background())
+}
+
+// GetUserCollectionsContext is like GetUserCollections, but uses ctx for the request.
+func (c *Client) GetUserCollectionsContext(ctx context.Context) (*[]Collection, error) {
 	colls := &[]Collection{}
-	env, err := c.get("/me/collections", colls)
+	endpoint := "/me/collections"
+	env, err := c.get(ctx, endpoint, colls)
 	if err != nil {
 		return nil, err
 	}
@@ -154,20 +278,66 @@ func (c *Client) GetUserCollections() (*[]Collection, error) {
 
 	if status != http.StatusOK {
 		if c.isNotLoggedIn(status) {
-			return nil, fmt.Errorf("Not authenticated.")
+			return nil, newAPIError("GET", endpoint, env, "Not authenticated.")
 		}
-		return nil, fmt.Errorf("Problem getting collections: %d. %v\n", status, err)
+		return nil, newAPIError("GET", endpoint, env, fmt.Sprintf("Problem getting collections: %d. %s", status, env.ErrorMessage))
 	}
 	return colls, nil
 }
 
+// UpdateCollection changes the settings of the given collection, returning
+// the updated Collection.
+func (c *Client) UpdateCollection(alias string, sp *CollectionUpdateParams) (*Collection, error) {
+	return c.UpdateCollectionContext(context.Background(), alias, sp)
+}
+
+// UpdateCollectionContext is like UpdateCollection, but uses ctx for the request.
+func (c *Client) UpdateCollectionContext(ctx context.Context, alias string, sp *CollectionUpdateParams) (*Collection, error) {
+	if sp.Visibility != nil && *sp.Visibility == VisibilityPassword && sp.Password == "" {
+		return nil, fmt.Errorf("A password is required for password-protected collections.")
+	}
+
+	coll := &Collection{}
+	endpoint := "/collections/" + alias
+	env, err := c.post(ctx, endpoint, sp, coll)
+	if err != nil {
+		return nil, err
+	}
+
+	var ok bool
+	if coll, ok = env.Data.(*Collection); !ok {
+		return nil, fmt.Errorf("Wrong data returned from API.")
+	}
+
+	status := env.Code
+	switch status {
+	case http.StatusOK:
+		return coll, nil
+	case http.StatusBadRequest:
+		return nil, newAPIError("POST", endpoint, env, fmt.Sprintf("Bad request: %s", env.ErrorMessage))
+	case http.StatusUnauthorized:
+		return nil, newAPIError("POST", endpoint, env, "Not authenticated.")
+	case http.StatusForbidden:
+		return nil, newAPIError("POST", endpoint, env, "You don't have permission to update this collection.")
+	case http.StatusNotFound:
+		return nil, newAPIError("POST", endpoint, env, "Collection not found.")
+	default:
+		return nil, newAPIError("POST", endpoint, env, fmt.Sprintf("Problem updating collection: %d. %s", status, env.ErrorMessage))
+	}
+}
+
 // DeleteCollection permanently deletes a collection and makes any posts on it
 // anonymous.
 //
 // See https://developers.write.as/docs/api/#delete-a-collection.
 func (c *Client) DeleteCollection(alias string) error {
+	return c.DeleteCollectionContext(context.Background(), alias)
+}
+
+// DeleteCollectionContext is like DeleteCollection, but uses ctx for the request.
+func (c *Client) DeleteCollectionContext(ctx context.Context, alias string) error {
 	endpoint := "/collections/" + alias
-	env, err := c.delete(endpoint, nil /* data */)
+	env, err := c.delete(ctx, endpoint, nil /* data */)
 	if err != nil {
 		return err
 	}
@@ -177,10 +347,10 @@ func (c *Client) DeleteCollection(alias string) error {
 	case http.StatusNoContent:
 		return nil
 	case http.StatusUnauthorized:
-		return fmt.Errorf("Not authenticated.")
+		return newAPIError("DELETE", endpoint, env, "Not authenticated.")
 	case http.StatusBadRequest:
-		return fmt.Errorf("Bad request: %s", env.ErrorMessage)
+		return newAPIError("DELETE", endpoint, env, fmt.Sprintf("Bad request: %s", env.ErrorMessage))
 	default:
-		return fmt.Errorf("Problem deleting collection: %d. %s\n", status, env.ErrorMessage)
+		return newAPIError("DELETE", endpoint, env, fmt.Sprintf("Problem deleting collection: %d. %s", status, env.ErrorMessage))
 	}
 }
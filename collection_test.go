@@ -1,14 +1,19 @@
 package writeas
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 	"testing"
 	"time"
 )
 
 func TestGetCollection(t *testing.T) {
-	dwac := NewDevClient()
+	srv := newTestServer()
+	defer srv.Close()
+	dwac := NewClientWith(Config{URL: srv.APIURL()})
 
 	res, err := dwac.GetCollection("tester")
 	if err != nil {
@@ -20,7 +25,9 @@ func TestGetCollection(t *testing.T) {
 }
 
 func TestGetCollectionPosts(t *testing.T) {
-	dwac := NewDevClient()
+	srv := newTestServer()
+	defer srv.Close()
+	dwac := NewClientWith(Config{URL: srv.APIURL()})
 	posts := []Post{}
 
 	t.Run("Get all posts in collection", func(t *testing.T) {
@@ -50,7 +57,9 @@ func TestGetCollectionPosts(t *testing.T) {
 }
 
 func TestGetUserCollections(t *testing.T) {
-	wac := NewDevClient()
+	srv := newTestServer()
+	defer srv.Close()
+	wac := NewClientWith(Config{URL: srv.APIURL()})
 	_, err := wac.LogIn("demo", "demo")
 	if err != nil {
 		t.Fatalf("Unable to log in: %v", err)
@@ -69,7 +78,9 @@ func TestGetUserCollections(t *testing.T) {
 }
 
 func TestCreateAndDeleteCollection(t *testing.T) {
-	wac := NewDevClient()
+	srv := newTestServer()
+	defer srv.Close()
+	wac := NewClientWith(Config{URL: srv.APIURL()})
 	_, err := wac.LogIn("demo", "demo")
 	if err != nil {
 		t.Fatalf("Unable to log in: %v", err)
@@ -92,7 +103,9 @@ func TestCreateAndDeleteCollection(t *testing.T) {
 }
 
 func TestDeleteCollectionUnauthenticated(t *testing.T) {
-	wac := NewDevClient()
+	srv := newTestServer()
+	defer srv.Close()
+	wac := NewClientWith(Config{URL: srv.APIURL()})
 
 	now := time.Now().Unix()
 	alias := fmt.Sprintf("test-collection-does-not-exist-%v", now)
@@ -105,3 +118,101 @@ func TestDeleteCollectionUnauthenticated(t *testing.T) {
 		t.Fatalf("Error message should be more informative: %v", err)
 	}
 }
+
+func TestUpdateCollection(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	srv.AddUser("other", "other")
+	srv.AddCollection("other", "not-mine", "Not Mine")
+	wac := NewClientWith(Config{URL: srv.APIURL()})
+	if _, err := wac.LogIn("demo", "demo"); err != nil {
+		t.Fatalf("Unable to log in: %v", err)
+	}
+
+	title, css, pointer := "New Title", "body { color: red; }", "$wallet.example.com/demo"
+	vis, format := VisibilityPrivate, FormatNovel
+	coll, err := wac.UpdateCollection("tester", &CollectionUpdateParams{
+		Title:               &title,
+		StyleSheet:          &css,
+		Visibility:          &vis,
+		Format:              &format,
+		MonetizationPointer: &pointer,
+	})
+	if err != nil {
+		t.Fatalf("Unable to update collection: %v", err)
+	}
+	if coll.Title != title || coll.StyleSheet != css || coll.Format != format || coll.MonetizationPointer != pointer {
+		t.Errorf("Collection wasn't updated: %+v", coll)
+	}
+	if !coll.Private || coll.Visibility != VisibilityPrivate {
+		t.Errorf("Collection should be private: %+v", coll)
+	}
+
+	// Fields that aren't given stay the same
+	desc := "A description"
+	coll, err = wac.UpdateCollection("tester", &CollectionUpdateParams{Description: &desc})
+	if err != nil {
+		t.Fatalf("Unable to update collection: %v", err)
+	}
+	if coll.Title != title || coll.Description != desc {
+		t.Errorf("Unexpected collection after partial update: %+v", coll)
+	}
+
+	t.Run("password required", func(t *testing.T) {
+		vis := VisibilityPassword
+		if _, err := wac.UpdateCollection("tester", &CollectionUpdateParams{Visibility: &vis}); err == nil {
+			t.Errorf("Expected error without a password")
+		}
+		coll, err := wac.UpdateCollection("tester", &CollectionUpdateParams{Visibility: &vis, Password: "secret"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if coll.Visibility != VisibilityPassword {
+			t.Errorf("Got visibility %d, want %d", coll.Visibility, VisibilityPassword)
+		}
+	})
+
+	t.Run("unlisted", func(t *testing.T) {
+		vis := VisibilityUnlisted
+		coll, err := wac.UpdateCollection("tester", &CollectionUpdateParams{Visibility: &vis})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if coll.Visibility != VisibilityUnlisted || coll.Private {
+			t.Errorf("Collection should be unlisted: %+v", coll)
+		}
+		// Unlisted is the zero value, but still needs to be written out,
+		// e.g. in exports
+		b, _ := json.Marshal(coll)
+		if !strings.Contains(string(b), `"visibility":0`) {
+			t.Errorf("Unlisted visibility left out of %s", b)
+		}
+	})
+
+	errTests := []struct {
+		name   string
+		alias  string
+		params *CollectionUpdateParams
+		status int
+	}{
+		{"bad format", "tester", &CollectionUpdateParams{Format: func() *CollectionFormat { f := CollectionFormat("scroll"); return &f }()}, http.StatusBadRequest},
+		{"not owner", "not-mine", &CollectionUpdateParams{Title: &title}, http.StatusForbidden},
+		{"not found", "nope", &CollectionUpdateParams{Title: &title}, http.StatusNotFound},
+	}
+	for _, test := range errTests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := wac.UpdateCollection(test.alias, test.params)
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != test.status {
+				t.Errorf("Expected %d error, got: %v", test.status, err)
+			}
+		})
+	}
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		_, err := wac.WithToken("").UpdateCollection("tester", &CollectionUpdateParams{Title: &title})
+		if !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("Expected ErrUnauthorized, got: %v", err)
+		}
+	})
+}
@@ -0,0 +1,180 @@
+package writeas
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// ErrBadPassphrase is returned when a credentials file can't be decrypted,
+// either because the passphrase is wrong or the file was tampered with.
+var ErrBadPassphrase = errors.New("wrong passphrase, or credentials file is corrupt")
+
+const (
+	credentialsVersion = 1
+
+	// scrypt cost parameters for newly saved credentials. They're stored in
+	// the file, so they can be raised later without breaking old files.
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+
+	// Limits on the scrypt parameters a credentials file can ask for, at
+	// most 4 times the cost of the ones above (128 MiB of memory). They're
+	// only authenticated once the key is derived, so without these a
+	// tampered file could make loading it use any amount of memory and time.
+	maxScryptN = 4 * scryptN
+	maxScryptR = scryptR
+	maxScryptP = 4 * scryptP
+)
+
+// credentialsFile is the format of a saved credentials file. Everything but
+// the ciphertext is stored in the clear, and authenticated along with it.
+type credentialsFile struct {
+	Version int    `json:"version"`
+	KDF     string `json:"kdf"`
+	N       int    `json:"n"`
+	R       int    `json:"r"`
+	P       int    `json:"p"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+// SaveCredentials encrypts the given user's credentials with a key derived
+// from passphrase, and writes them to the file at path, replacing it. The
+// file is only readable by its owner. Load them again with LoadCredentials.
+//
+//	u, err := c.LogIn(username, password)
+//	// ...
+//	err = writeas.SaveCredentials("credentials.json", passphrase, u)
+//
+// Only the access token and user are saved; any password is left out.
+func SaveCredentials(path string, passphrase []byte, u *AuthUser) error {
+	return withFileLock(path, func() error {
+		return saveCredentials(path, passphrase, u)
+	})
+}
+
+// LoadCredentials decrypts the credentials saved in the file at path with
+// SaveCredentials. It returns ErrBadPassphrase if passphrase is wrong. Use
+// the result's AccessToken with Client.SetToken or Client.WithToken.
+func LoadCredentials(path string, passphrase []byte) (*AuthUser, error) {
+	var u *AuthUser
+	err := withFileLock(path, func() error {
+		var err error
+		u, err = loadCredentials(path, passphrase)
+		return err
+	})
+	return u, err
+}
+
+// RotateCredentialsPassphrase re-encrypts the credentials saved in the file
+// at path with a key derived from a new passphrase. It returns
+// ErrBadPassphrase, leaving the file as it was, if oldPassphrase is wrong.
+func RotateCredentialsPassphrase(path string, oldPassphrase, newPassphrase []byte) error {
+	return withFileLock(path, func() error {
+		u, err := loadCredentials(path, oldPassphrase)
+		if err != nil {
+			return err
+		}
+		return saveCredentials(path, newPassphrase, u)
+	})
+}
+
+func saveCredentials(path string, passphrase []byte, u *AuthUser) error {
+	plain, err := json.Marshal(&AuthUser{AccessToken: u.AccessToken, User: u.User})
+	if err != nil {
+		return err
+	}
+
+	f := &credentialsFile{
+		Version: credentialsVersion,
+		KDF:     "scrypt",
+		N:       scryptN,
+		R:       scryptR,
+		P:       scryptP,
+		Salt:    make([]byte, 16),
+	}
+	if _, err := rand.Read(f.Salt); err != nil {
+		return err
+	}
+	aead, err := f.aead(passphrase)
+	if err != nil {
+		return err
+	}
+	f.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(f.Nonce); err != nil {
+		return err
+	}
+	f.Data = aead.Seal(nil, f.Nonce, plain, f.additionalData())
+
+	b, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(b, '\n'))
+}
+
+func loadCredentials(path string, passphrase []byte) (*AuthUser, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f := &credentialsFile{}
+	if err := json.Unmarshal(b, f); err != nil {
+		return nil, fmt.Errorf("parse credentials: %v", err)
+	}
+	if f.Version != credentialsVersion || f.KDF != "scrypt" {
+		return nil, fmt.Errorf("unsupported credentials file version %d (%s)", f.Version, f.KDF)
+	}
+
+	aead, err := f.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if len(f.Nonce) != aead.NonceSize() {
+		return nil, ErrBadPassphrase
+	}
+	plain, err := aead.Open(nil, f.Nonce, f.Data, f.additionalData())
+	if err != nil {
+		return nil, ErrBadPassphrase
+	}
+	u := &AuthUser{}
+	if err := json.Unmarshal(plain, u); err != nil {
+		return nil, fmt.Errorf("parse credentials: %v", err)
+	}
+	return u, nil
+}
+
+// aead derives the file's key from passphrase and returns an AES-256-GCM
+// cipher using it. It returns ErrBadPassphrase if the file's scrypt
+// parameters are invalid or above the limits, as they can only have been
+// tampered with.
+func (f *credentialsFile) aead(passphrase []byte) (cipher.AEAD, error) {
+	if f.N < 2 || f.N&(f.N-1) != 0 || f.N > maxScryptN ||
+		f.R < 1 || f.R > maxScryptR || f.P < 1 || f.P > maxScryptP {
+		return nil, ErrBadPassphrase
+	}
+	key, err := scrypt.Key(passphrase, f.Salt, f.N, f.R, f.P, 32)
+	if err != nil {
+		return nil, fmt.Errorf("derive key: %v", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// additionalData binds the file's parameters to its ciphertext, so they
+// can't be changed without detection.
+func (f *credentialsFile) additionalData() []byte {
+	return []byte(fmt.Sprintf("writeas-credentials:%d:%s:%d:%d:%d:%x", f.Version, f.KDF, f.N, f.R, f.P, f.Salt))
+}
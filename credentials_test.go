@@ -0,0 +1,116 @@
+package writeas
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "writeas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "credentials.json")
+
+	u := &AuthUser{
+		AccessToken: "00000000-0000-0000-0000-000000000000",
+		Password:    "generated-password",
+		User:        &User{Username: "demo"},
+	}
+	if err := SaveCredentials(path, []byte("correct horse"), u); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(b, []byte(u.AccessToken)) || bytes.Contains(b, []byte("demo")) {
+		t.Errorf("Credentials file contains plaintext: %s", b)
+	}
+	if runtime.GOOS != "windows" {
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := fi.Mode().Perm(); perm != 0600 {
+			t.Errorf("Credentials file has permissions %v, want 0600", perm)
+		}
+	}
+
+	got, err := LoadCredentials(path, []byte("correct horse"))
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if got.AccessToken != u.AccessToken || got.User == nil || got.User.Username != "demo" {
+		t.Errorf("Loaded %+v, want %+v", got, u)
+	}
+	if got.Password != "" {
+		t.Errorf("Password was saved: %q", got.Password)
+	}
+
+	if _, err := LoadCredentials(path, []byte("wrong")); !errors.Is(err, ErrBadPassphrase) {
+		t.Errorf("Expected ErrBadPassphrase, got: %v", err)
+	}
+
+	t.Run("rotate", func(t *testing.T) {
+		if err := RotateCredentialsPassphrase(path, []byte("wrong"), []byte("new")); !errors.Is(err, ErrBadPassphrase) {
+			t.Errorf("Expected ErrBadPassphrase rotating with wrong passphrase, got: %v", err)
+		}
+		if err := RotateCredentialsPassphrase(path, []byte("correct horse"), []byte("battery staple")); err != nil {
+			t.Fatalf("Rotate failed: %v", err)
+		}
+		if _, err := LoadCredentials(path, []byte("correct horse")); !errors.Is(err, ErrBadPassphrase) {
+			t.Errorf("Old passphrase still works after rotating, got: %v", err)
+		}
+		got, err := LoadCredentials(path, []byte("battery staple"))
+		if err != nil {
+			t.Fatalf("Load with new passphrase failed: %v", err)
+		}
+		if got.AccessToken != u.AccessToken {
+			t.Errorf("Got token %q after rotating, want %q", got.AccessToken, u.AccessToken)
+		}
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		orig, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// A lowered cost fails authentication, while one that's too high or
+		// invalid is rejected before deriving a key
+		for _, param := range []struct {
+			name  string
+			value int
+		}{
+			{"n", 1 << 10},
+			{"n", 1 << 20},
+			{"n", 3 << 14},
+			{"n", 0},
+			{"r", 1 << 20},
+			{"r", 0},
+			{"p", 1 << 20},
+			{"p", -1},
+		} {
+			f := map[string]interface{}{}
+			if err := json.Unmarshal(orig, &f); err != nil {
+				t.Fatal(err)
+			}
+			f[param.name] = param.value
+			b, _ := json.Marshal(f)
+			if err := ioutil.WriteFile(path, b, 0600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadCredentials(path, []byte("battery staple")); !errors.Is(err, ErrBadPassphrase) {
+				t.Errorf("Expected ErrBadPassphrase with %s = %d, got: %v", param.name, param.value, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,87 @@
+package writeas
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/writeas/impart"
+)
+
+// Sentinel errors that an *APIError can be compared against with errors.Is.
+var (
+	// ErrNotFound means the requested resource doesn't exist.
+	ErrNotFound = errors.New("not found")
+	// ErrGone means the requested resource existed, but was removed or
+	// unpublished.
+	ErrGone = errors.New("gone")
+	// ErrUnauthorized means the request required valid credentials that
+	// weren't given.
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrRateLimited means too many requests were made in a short period of
+	// time.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrConflict means the request conflicts with an existing resource, e.g.
+	// a collection alias that's already taken.
+	ErrConflict = errors.New("conflict")
+	// ErrQuotaExceeded means the user has reached a limit on their account,
+	// e.g. the maximum number of collections.
+	ErrQuotaExceeded = errors.New("quota exceeded")
+)
+
+// APIError is returned whenever the Write.as API responds with an
+// unsuccessful status code. Use errors.As to inspect it, or errors.Is with one
+// of the sentinel errors above to check for a specific kind of failure.
+type APIError struct {
+	// StatusCode is the HTTP status code returned by the API.
+	StatusCode int
+	// Message is the error message returned by the API, if any.
+	Message string
+
+	// Method and Endpoint identify the request that failed.
+	Method   string
+	Endpoint string
+
+	// desc is the user-friendly description returned by Error.
+	desc string
+}
+
+func newAPIError(method, endpoint string, env *impart.Envelope, desc string) *APIError {
+	return &APIError{
+		StatusCode: env.Code,
+		Message:    env.ErrorMessage,
+		Method:     method,
+		Endpoint:   endpoint,
+		desc:       desc,
+	}
+}
+
+func (e *APIError) Error() string {
+	if e.desc != "" {
+		return e.desc
+	}
+	if e.Message != "" {
+		return fmt.Sprintf("%s %s: %d. %s", e.Method, e.Endpoint, e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("%s %s: %d", e.Method, e.Endpoint, e.StatusCode)
+}
+
+// Is reports whether the error's status code corresponds to the given
+// sentinel error.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrGone:
+		return e.StatusCode == http.StatusGone
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrQuotaExceeded:
+		return e.StatusCode == http.StatusPreconditionFailed
+	}
+	return false
+}
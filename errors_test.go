@@ -0,0 +1,98 @@
+package writeas
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newStatusServer(code int, msg string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		fmt.Fprintf(w, `{"code":%d,"error_msg":%q}`, code, msg)
+	}))
+}
+
+func TestAPIErrorSentinels(t *testing.T) {
+	tests := []struct {
+		code     int
+		sentinel error
+	}{
+		{http.StatusNotFound, ErrNotFound},
+		{http.StatusGone, ErrGone},
+		{http.StatusUnauthorized, ErrUnauthorized},
+		{http.StatusTooManyRequests, ErrRateLimited},
+		{http.StatusConflict, ErrConflict},
+		{http.StatusPreconditionFailed, ErrQuotaExceeded},
+	}
+	for _, test := range tests {
+		t.Run(http.StatusText(test.code), func(t *testing.T) {
+			srv := newStatusServer(test.code, "Nope.")
+			defer srv.Close()
+			c := NewClientWith(Config{URL: srv.URL})
+
+			_, err := c.CreateCollection(&CollectionParams{Alias: "blog"})
+			if !errors.Is(err, test.sentinel) {
+				t.Fatalf("Expected %v, got: %v", test.sentinel, err)
+			}
+			for _, other := range tests {
+				if other.sentinel != test.sentinel && errors.Is(err, other.sentinel) {
+					t.Errorf("Error %v shouldn't match %v", err, other.sentinel)
+				}
+			}
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("Expected *APIError, got %T", err)
+			}
+			if apiErr.StatusCode != test.code {
+				t.Errorf("StatusCode = %d, want %d", apiErr.StatusCode, test.code)
+			}
+			if apiErr.Message != "Nope." {
+				t.Errorf("Message = %q, want %q", apiErr.Message, "Nope.")
+			}
+			if apiErr.Method != "POST" || apiErr.Endpoint != "/collections" {
+				t.Errorf("Unexpected request: %s %s", apiErr.Method, apiErr.Endpoint)
+			}
+		})
+	}
+}
+
+func TestAPIErrorEndpoints(t *testing.T) {
+	srv := newStatusServer(http.StatusNotFound, "")
+	defer srv.Close()
+	c := NewClientWith(Config{URL: srv.URL})
+	c.SetToken("00000000-0000-0000-0000-000000000000")
+
+	tests := []struct {
+		name     string
+		call     func() error
+		method   string
+		endpoint string
+	}{
+		{"GetPost", func() error { _, err := c.GetPost("abc"); return err }, "GET", "/posts/abc"},
+		{"UpdatePost", func() error { _, err := c.UpdatePost("abc", "", &PostParams{}); return err }, "PUT", "/posts/abc"},
+		{"DeletePost", func() error { return c.DeletePost("abc", "") }, "DELETE", "/posts/abc"},
+		{"GetCollectionPost", func() error { _, err := c.GetCollectionPost("blog", "hi"); return err }, "GET", "/collections/blog/posts/hi"},
+		{"DeleteCollection", func() error { return c.DeleteCollection("blog") }, "DELETE", "/collections/blog"},
+		{"LogIn", func() error { _, err := c.LogIn("user", "pass"); return err }, "POST", "/auth/login"},
+		{"GetMe", func() error { _, err := c.GetMe(false); return err }, "GET", "/me"},
+		{"Markdown", func() error { _, err := c.Markdown("*hi*", ""); return err }, "POST", "/markdown"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.call()
+			if !errors.Is(err, ErrNotFound) {
+				t.Fatalf("Expected ErrNotFound, got: %v", err)
+			}
+			var apiErr *APIError
+			errors.As(err, &apiErr)
+			if apiErr.Method != test.method || apiErr.Endpoint != test.endpoint {
+				t.Errorf("Got %s %s, want %s %s", apiErr.Method, apiErr.Endpoint, test.method, test.endpoint)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package writeas
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// draftsDir is the directory exported posts that aren't in a collection are
+// written to.
+const draftsDir = "_drafts"
+
+// Export is a complete backup of a user's collections and posts, as
+// returned by the API.
+type Export struct {
+	User    *User     `json:"user"`
+	Created time.Time `json:"created"`
+
+	// Collections are all of the user's collections, each with every one of
+	// its posts.
+	Collections []Collection `json:"collections"`
+	// Drafts are the user's posts that aren't in any collection.
+	Drafts []Post `json:"drafts"`
+}
+
+// Export retrieves everything needed for a complete backup of the
+// authenticated user's collections and posts. Write it out with WriteJSON,
+// WriteMarkdown or WriteZip.
+func (c *Client) Export(ctx context.Context) (*Export, error) {
+	u, err := c.GetMeContext(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	e := &Export{
+		User:        u,
+		Created:     time.Now().UTC(),
+		Collections: []Collection{},
+		Drafts:      []Post{},
+	}
+
+	colls, err := c.GetUserCollectionsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, coll := range *colls {
+		posts := []Post{}
+		it := c.CollectionPosts(ctx, coll.Alias)
+		for it.Next() {
+			posts = append(posts, *it.Post())
+		}
+		if err := it.Err(); err != nil {
+			return nil, err
+		}
+		coll.Posts = &posts
+		e.Collections = append(e.Collections, coll)
+	}
+
+	posts, err := c.GetUserPostsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range *posts {
+		if p.Collection == nil {
+			e.Drafts = append(e.Drafts, p)
+		}
+	}
+	return e, nil
+}
+
+// WriteJSON writes the whole export to w as JSON, using the same format as
+// the API's Post and Collection objects.
+func (e *Export) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(e)
+}
+
+// WriteMarkdown writes the export into dir as Markdown files with YAML front
+// matter, one directory per collection:
+//
+//	export.json
+//	blog/collection.json
+//	blog/my-first-post.md
+//	_drafts/abc123def456.md
+//
+// Collection posts are named after their slug, and drafts after their ID.
+// Posts with a slug that isn't safe to use as a file name are named after
+// their ID instead. Existing files are overwritten.
+func (e *Export) WriteMarkdown(dir string) error {
+	return e.files(func(name string, b []byte) error {
+		fn, err := joinWithin(dir, filepath.FromSlash(name))
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(fn), 0755); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(fn, b, 0644)
+	})
+}
+
+// WriteZip writes the export to w as a ZIP archive, with the same layout as
+// WriteMarkdown.
+func (e *Export) WriteZip(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	err := e.files(func(name string, b []byte) error {
+		f, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: e.Created,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(b)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// files calls fn with the slash-separated name and contents of every file in
+// the export's Markdown layout. It fails if a collection alias or post ID
+// can't be used as a file name.
+func (e *Export) files(fn func(name string, b []byte) error) error {
+	var buf bytes.Buffer
+	if err := e.WriteJSON(&buf); err != nil {
+		return err
+	}
+	if err := fn("export.json", buf.Bytes()); err != nil {
+		return err
+	}
+
+	for _, coll := range e.Collections {
+		if !safePathComponent(coll.Alias) {
+			return fmt.Errorf("unsafe collection alias %q", coll.Alias)
+		}
+		// Leave posts out of the collection's own file, since they each
+		// get one
+		meta := coll
+		meta.Posts = nil
+		b, err := json.MarshalIndent(meta, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := fn(path.Join(coll.Alias, "collection.json"), append(b, '\n')); err != nil {
+			return err
+		}
+		if coll.Posts == nil {
+			continue
+		}
+		for i := range *coll.Posts {
+			p := &(*coll.Posts)[i]
+			name, err := exportName(p)
+			if err != nil {
+				return err
+			}
+			if err := fn(path.Join(coll.Alias, name), p.markdown(coll.Alias)); err != nil {
+				return err
+			}
+		}
+	}
+	for i := range e.Drafts {
+		p := &e.Drafts[i]
+		name, err := exportName(p)
+		if err != nil {
+			return err
+		}
+		if err := fn(path.Join(draftsDir, name), p.markdown("")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportName returns the file name for the given post in an export: its slug
+// if it has a safe one, or else its ID.
+func exportName(p *Post) (string, error) {
+	if p.Slug != "" && safePathComponent(p.Slug) {
+		return p.Slug + ".md", nil
+	}
+	if !safePathComponent(p.ID) {
+		return "", fmt.Errorf("unsafe post ID %q", p.ID)
+	}
+	return p.ID + ".md", nil
+}
+
+// markdown returns the post as Markdown with YAML front matter.
+func (p *Post) markdown(collection string) []byte {
+	var b bytes.Buffer
+	field := func(k, v string) {
+		b.WriteString(k + ": " + v + "\n")
+	}
+
+	b.WriteString("---\n")
+	field("id", strconv.Quote(p.ID))
+	if p.Slug != "" {
+		field("slug", strconv.Quote(p.Slug))
+	}
+	if p.Title != "" {
+		field("title", strconv.Quote(p.Title))
+	}
+	field("date", p.Created.UTC().Format(time.RFC3339))
+	if !p.Updated.IsZero() {
+		field("updated", p.Updated.UTC().Format(time.RFC3339))
+	}
+	if collection != "" {
+		field("collection", strconv.Quote(collection))
+	}
+	if p.Language != nil && *p.Language != "" {
+		field("lang", strconv.Quote(*p.Language))
+	}
+	if p.RTL != nil {
+		field("rtl", strconv.FormatBool(*p.RTL))
+	}
+	if p.Font != "" {
+		field("font", strconv.Quote(p.Font))
+	}
+	// The owner's username isn't necessarily an author slug, so it's kept
+	// under a key that isn't imported
+	if p.OwnerName != "" {
+		field("owner", strconv.Quote(p.OwnerName))
+	}
+	if len(p.Tags) > 0 {
+		b.WriteString("tags:\n")
+		for _, t := range p.Tags {
+			b.WriteString("  - " + strconv.Quote(t) + "\n")
+		}
+	}
+	b.WriteString("---\n\n")
+
+	b.WriteString(p.Content)
+	if len(p.Content) > 0 && p.Content[len(p.Content)-1] != '\n' {
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
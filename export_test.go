@@ -0,0 +1,197 @@
+package writeas
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExport(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	// More than one page of collection posts
+	for i := 0; i < 12; i++ {
+		srv.AddCollectionPost("tester", "", "Filler post.")
+	}
+	c := NewClientWith(Config{URL: srv.APIURL()})
+	if _, err := c.LogIn("demo", "demo"); err != nil {
+		t.Fatalf("Unable to log in: %v", err)
+	}
+	draft, err := c.CreatePost(&PostParams{Title: "A \"draft\"", Content: "Not published yet."})
+	if err != nil {
+		t.Fatalf("Unable to create draft: %v", err)
+	}
+
+	e, err := c.Export(context.Background())
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if e.User == nil || e.User.Username != "demo" {
+		t.Errorf("Exported user = %+v, want demo", e.User)
+	}
+	if len(e.Collections) != 1 || e.Collections[0].Posts == nil || len(*e.Collections[0].Posts) != 14 {
+		t.Fatalf("Expected 1 collection with 14 posts, got: %+v", e.Collections)
+	}
+	if len(e.Drafts) != 1 || e.Drafts[0].ID != draft.ID {
+		t.Fatalf("Expected draft %s, got: %+v", draft.ID, e.Drafts)
+	}
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := e.WriteJSON(&buf); err != nil {
+			t.Fatalf("WriteJSON failed: %v", err)
+		}
+		got := &Export{}
+		if err := json.Unmarshal(buf.Bytes(), got); err != nil {
+			t.Fatalf("Unable to parse export: %v", err)
+		}
+		if len(*got.Collections[0].Posts) != 14 || got.Drafts[0].Content != draft.Content {
+			t.Errorf("Export didn't survive a round trip: %+v", got)
+		}
+	})
+
+	t.Run("zip", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := e.WriteZip(&buf); err != nil {
+			t.Fatalf("WriteZip failed: %v", err)
+		}
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatalf("Invalid ZIP: %v", err)
+		}
+		files := map[string]string{}
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, _ := ioutil.ReadAll(rc)
+			rc.Close()
+			files[f.Name] = string(b)
+		}
+		if len(files) != 17 {
+			t.Errorf("Got %d files, want 17", len(files))
+		}
+		if _, ok := files["tester/collection.json"]; !ok {
+			t.Errorf("Missing collection.json")
+		}
+		post, ok := files["tester/first-post.md"]
+		if !ok {
+			t.Fatalf("Missing first-post.md")
+		}
+		for _, want := range []string{"---\n", `title: "First post"`, `collection: "tester"`, "\n---\n\nThis is the first post.\n"} {
+			if !strings.Contains(post, want) {
+				t.Errorf("first-post.md missing %q:\n%s", want, post)
+			}
+		}
+		if d := files["_drafts/"+draft.ID+".md"]; !strings.Contains(d, `title: "A \"draft\""`) {
+			t.Errorf("Draft title not quoted correctly:\n%s", d)
+		}
+	})
+
+	t.Run("markdown", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "writeas")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		if err := e.WriteMarkdown(dir); err != nil {
+			t.Fatalf("WriteMarkdown failed: %v", err)
+		}
+		matches, _ := filepath.Glob(filepath.Join(dir, "tester", "*.md"))
+		if len(matches) != 14 {
+			t.Errorf("Got %d Markdown files, want 14", len(matches))
+		}
+	})
+}
+
+func TestExportHostileNames(t *testing.T) {
+	parent, err := ioutil.TempDir("", "writeas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(parent)
+	dir := filepath.Join(parent, "export")
+
+	posts := []Post{{ID: "abc123", Slug: "../../evil", Content: "Gotcha"}}
+	e := &Export{Collections: []Collection{{Alias: "blog", Posts: &posts}}}
+	if err := e.WriteMarkdown(dir); err != nil {
+		t.Fatalf("WriteMarkdown failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "blog", "abc123.md")); err != nil {
+		t.Errorf("Post with unsafe slug not named after its ID: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(parent, "evil.md")); !os.IsNotExist(err) {
+		t.Errorf("Post written outside of the export directory")
+	}
+
+	var buf bytes.Buffer
+	if err := e.WriteZip(&buf); err != nil {
+		t.Fatalf("WriteZip failed: %v", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Invalid ZIP: %v", err)
+	}
+	for _, f := range zr.File {
+		if strings.Contains(f.Name, "..") {
+			t.Errorf("Unsafe ZIP entry %q", f.Name)
+		}
+	}
+
+	for _, e := range []*Export{
+		{Collections: []Collection{{Alias: "..", Posts: &[]Post{}}}},
+		{Drafts: []Post{{ID: "../evil"}}},
+	} {
+		if err := e.WriteMarkdown(dir); err == nil {
+			t.Errorf("Expected error writing %+v", e)
+		}
+		if err := e.WriteZip(ioutil.Discard); err == nil {
+			t.Errorf("Expected error zipping %+v", e)
+		}
+	}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	created := time.Date(2020, 3, 4, 5, 6, 7, 0, time.UTC)
+	updated := created.Add(time.Hour)
+	lang, rtl := "en", false
+	p := &Post{
+		ID:        "abc123",
+		Slug:      "hello",
+		Title:     "Hello",
+		Content:   "Body.",
+		Created:   created,
+		Updated:   updated,
+		Language:  &lang,
+		RTL:       &rtl,
+		OwnerName: "matt",
+		Tags:      []string{"greetings"},
+	}
+
+	mp, err := ParseMarkdownPost(p.markdown("blog"))
+	if err != nil {
+		t.Fatalf("Unable to parse exported post: %v", err)
+	}
+	sp := mp.Params()
+	if sp.ID != p.ID || sp.Slug != p.Slug || sp.Title != p.Title || sp.Content != "Body.\n" || sp.Collection != "blog" {
+		t.Errorf("Post didn't survive a round trip: %+v", sp)
+	}
+	if !sp.Created.Equal(created) || !sp.Updated.Equal(updated) || *sp.Language != lang || *sp.IsRTL != rtl {
+		t.Errorf("Metadata didn't survive a round trip: %+v", sp)
+	}
+	if len(sp.Categories) != 1 || sp.Categories[0].Slug != "greetings" {
+		t.Errorf("Got categories %+v", sp.Categories)
+	}
+	// The owner isn't necessarily an author on the collection
+	if sp.AuthorSlug != nil {
+		t.Errorf("Owner %q imported as the author", *sp.AuthorSlug)
+	}
+}
@@ -0,0 +1,66 @@
+package writeas
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// withFileLock calls fn while holding an exclusive lock on a lock file next
+// to the file at path. The file itself can't be locked, since it's replaced
+// on every write.
+func withFileLock(path string, fn func() error) error {
+	f, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := lockFile(f); err != nil {
+		return err
+	}
+	defer unlockFile(f)
+
+	return fn()
+}
+
+// writeFileAtomic replaces the file at path with one containing b, readable
+// only by its owner. Readers never see a partly written file.
+func writeFileAtomic(path string, b []byte) error {
+	// TempFile creates the file with 0600 permissions
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// safePathComponent reports whether name, which may come from the server,
+// can be used as a single file name without reaching outside its directory.
+func safePathComponent(name string) bool {
+	return name != "" && name != "." && !strings.Contains(name, "..") && !strings.ContainsAny(name, "/\\\x00")
+}
+
+// joinWithin joins the slash-separated path rel onto dir, failing if the
+// result isn't inside dir.
+func joinWithin(dir, rel string) (string, error) {
+	fn := filepath.Join(dir, filepath.FromSlash(rel))
+	r, err := filepath.Rel(dir, fn)
+	if err != nil || r == "." || r == ".." || strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %q is outside %s", rel, dir)
+	}
+	return fn, nil
+}
@@ -1,6 +1,7 @@
 package writeas
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 )
@@ -12,6 +13,11 @@ type BodyResponse struct {
 // Markdown takes raw Markdown and renders it into usable HTML. See
 // https://developers.write.as/docs/api/#render-markdown.
 func (c *Client) Markdown(body, collectionURL string) (string, error) {
+	return c.MarkdownContext(context.Background(), body, collectionURL)
+}
+
+// MarkdownContext is like Markdown, but uses ctx for the request.
+func (c *Client) MarkdownContext(ctx context.Context, body, collectionURL string) (string, error) {
 	p := &BodyResponse{}
 	data := struct {
 		RawBody       string `json:"raw_body"`
@@ -21,7 +27,8 @@ func (c *Client) Markdown(body, collectionURL string) (string, error) {
 		CollectionURL: collectionURL,
 	}
 
-	env, err := c.post("/markdown", data, p)
+	endpoint := "/markdown"
+	env, err := c.post(ctx, endpoint, data, p)
 	if err != nil {
 		return "", err
 	}
@@ -33,7 +40,7 @@ func (c *Client) Markdown(body, collectionURL string) (string, error) {
 	status := env.Code
 
 	if status != http.StatusOK {
-		return "", fmt.Errorf("Problem getting markdown: %d. %s\n", status, env.ErrorMessage)
+		return "", newAPIError("POST", endpoint, env, fmt.Sprintf("Problem getting markdown: %d. %s", status, env.ErrorMessage))
 	}
 	return p.Body, nil
 }
@@ -5,7 +5,9 @@ import (
 )
 
 func TestMarkdown(t *testing.T) {
-	dwac := NewDevClient()
+	srv := newTestServer()
+	defer srv.Close()
+	dwac := NewClientWith(Config{URL: srv.APIURL()})
 
 	in := "This is *formatted* in __Markdown__."
 	out := `<p>This is <em>formatted</em> in <strong>Markdown</strong>.</p>
@@ -0,0 +1,336 @@
+package writeas
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MarkdownPost is a post read from a Markdown file with front matter, like
+// the ones written by Export.WriteMarkdown.
+//
+// Front matter is either YAML, between "---" lines, or TOML, between "+++"
+// lines. Only the simple subset of each that's typical of front matter is
+// supported: one "key: value" (or "key = value") per line, with strings,
+// booleans, dates, and lists of strings, either inline or, in YAML, as
+// "- item" lines. Unknown keys are ignored.
+type MarkdownPost struct {
+	// ID, Token and Slug identify the published post, once there is one.
+	ID    string
+	Token string
+	Slug  string
+
+	Title      string
+	Date       *time.Time
+	Updated    *time.Time
+	Tags       []string
+	Language   *string
+	RTL        *bool
+	Font       string
+	Collection string
+	Author     string
+
+	// Content is the post body, following the front matter.
+	Content string
+}
+
+// frontMatterDates are the formats accepted for dates in front matter.
+var frontMatterDates = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// ParseMarkdownPost parses a Markdown file with optional front matter.
+func ParseMarkdownPost(b []byte) (*MarkdownPost, error) {
+	delim, lines, body := splitFrontMatter(b)
+	if delim == "" {
+		return &MarkdownPost{Content: body}, nil
+	}
+	// Drop the blank line that usually separates the front matter from the
+	// body
+	body = strings.TrimPrefix(strings.TrimPrefix(body, "\r"), "\n")
+	mp := &MarkdownPost{Content: body}
+
+	fields, err := parseFrontMatter(delim, lines)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range fields {
+		var err error
+		switch k {
+		case "id":
+			mp.ID = v.str()
+		case "token":
+			mp.Token = v.str()
+		case "slug":
+			mp.Slug = v.str()
+		case "title":
+			mp.Title = v.str()
+		case "date", "created":
+			mp.Date, err = v.date()
+		case "updated", "lastmod":
+			mp.Updated, err = v.date()
+		case "tags":
+			mp.Tags = v.list
+			if !v.isList && v.s != "" {
+				mp.Tags = []string{v.s}
+			}
+		case "lang", "language":
+			s := v.str()
+			mp.Language = &s
+		case "rtl":
+			var rtl bool
+			rtl, err = strconv.ParseBool(v.str())
+			mp.RTL = &rtl
+		case "font":
+			mp.Font = v.str()
+		case "collection":
+			mp.Collection = v.str()
+		case "author":
+			mp.Author = v.str()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("front matter %s: %v", k, err)
+		}
+	}
+	return mp, nil
+}
+
+// Params returns PostParams for creating or updating the post. Tags become
+// the post's categories.
+func (mp *MarkdownPost) Params() *PostParams {
+	sp := &PostParams{
+		ID:         mp.ID,
+		Token:      mp.Token,
+		Slug:       mp.Slug,
+		Created:    mp.Date,
+		Updated:    mp.Updated,
+		Title:      mp.Title,
+		Content:    mp.Content,
+		Font:       mp.Font,
+		IsRTL:      mp.RTL,
+		Language:   mp.Language,
+		Collection: mp.Collection,
+	}
+	if mp.Author != "" {
+		author := mp.Author
+		sp.AuthorSlug = &author
+	}
+	for _, t := range mp.Tags {
+		sp.Categories = append(sp.Categories, NewCategory(t))
+	}
+	return sp
+}
+
+// frontMatterValue is a single parsed front matter value.
+type frontMatterValue struct {
+	s      string
+	list   []string
+	isList bool
+}
+
+func (v frontMatterValue) str() string {
+	if v.isList {
+		return strings.Join(v.list, ", ")
+	}
+	return v.s
+}
+
+func (v frontMatterValue) date() (*time.Time, error) {
+	for _, layout := range frontMatterDates {
+		if t, err := time.Parse(layout, v.str()); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("unrecognized date %q", v.str())
+}
+
+// splitFrontMatter splits a file into its front matter delimiter ("---" or
+// "+++", or "" if there is no front matter), the lines between the
+// delimiters, and everything after the closing delimiter.
+func splitFrontMatter(b []byte) (delim string, lines []string, rest string) {
+	s := strings.TrimPrefix(string(b), "\ufeff")
+	first := s
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		first = s[:i]
+	}
+	delim = strings.TrimSpace(first)
+	if delim != "---" && delim != "+++" {
+		return "", nil, s
+	}
+
+	after := strings.TrimPrefix(s[len(first):], "\n")
+	all := strings.Split(after, "\n")
+	for i, l := range all {
+		if strings.TrimSpace(l) == delim {
+			return delim, all[:i], strings.Join(all[i+1:], "\n")
+		}
+	}
+	// No closing delimiter, so this isn't front matter after all
+	return "", nil, s
+}
+
+func parseFrontMatter(delim string, lines []string) (map[string]frontMatterValue, error) {
+	fields := map[string]frontMatterValue{}
+	listKey := ""
+	for n, l := range lines {
+		l = strings.TrimRight(l, "\r")
+		t := strings.TrimSpace(l)
+		if t == "" || strings.HasPrefix(t, "#") {
+			continue
+		}
+
+		// YAML block list items
+		if delim == "---" && listKey != "" && strings.HasPrefix(t, "-") && (l[0] == ' ' || l[0] == '\t' || l[0] == '-') {
+			v := fields[listKey]
+			v.list = append(v.list, parseScalar(strings.TrimSpace(t[1:])))
+			fields[listKey] = v
+			continue
+		}
+		listKey = ""
+
+		sep := ":"
+		if delim == "+++" {
+			sep = "="
+		}
+		i := strings.Index(t, sep)
+		if i < 0 {
+			return nil, fmt.Errorf("front matter line %d: expected %q", n+2, sep)
+		}
+		k := strings.ToLower(strings.Trim(strings.TrimSpace(t[:i]), `"'`))
+		val := strings.TrimSpace(t[i+1:])
+		switch {
+		case val == "" && delim == "---":
+			listKey = k
+			fields[k] = frontMatterValue{isList: true}
+		case strings.HasPrefix(val, "["):
+			fields[k] = frontMatterValue{list: parseInlineList(val), isList: true}
+		default:
+			fields[k] = frontMatterValue{s: parseScalar(val)}
+		}
+	}
+	return fields, nil
+}
+
+// parseScalar parses a quoted or bare string value.
+func parseScalar(s string) string {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		if end := closingQuote(s); end > 0 {
+			if u, err := strconv.Unquote(s[:end+1]); err == nil {
+				return u
+			}
+			return s[1:end]
+		}
+	case strings.HasPrefix(s, "'"):
+		if end := closingQuote(s); end > 0 {
+			return strings.Replace(s[1:end], "''", "'", -1)
+		}
+	}
+	// Strip any trailing comment
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+// closingQuote returns the index of the quote closing the string that s
+// starts with, or -1.
+func closingQuote(s string) int {
+	q := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case q == '"' && s[i] == '\\':
+			i++
+		case q == '\'' && s[i] == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++
+		case s[i] == q:
+			return i
+		}
+	}
+	return -1
+}
+
+// parseInlineList parses a list like [a, "b", 'c'].
+func parseInlineList(s string) []string {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "[")
+	if i := strings.LastIndex(s, "]"); i >= 0 {
+		s = s[:i]
+	}
+
+	list := []string{}
+	for {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return list
+		}
+		// Find the comma ending this item, skipping any inside quotes
+		from := 0
+		if s[0] == '"' || s[0] == '\'' {
+			if q := closingQuote(s); q > 0 {
+				from = q
+			}
+		}
+		item := s
+		s = ""
+		if i := strings.IndexByte(item[from:], ','); i >= 0 {
+			item, s = item[:from+i], item[from+i+1:]
+		}
+		list = append(list, parseScalar(strings.TrimSpace(item)))
+	}
+}
+
+// setFrontMatter returns the file b with the given front matter fields set,
+// replacing existing values for the same keys and leaving everything else
+// as it was. Fields with empty values are skipped. Front matter is added if
+// the file doesn't have any yet.
+func setFrontMatter(b []byte, fields [][2]string) []byte {
+	delim, lines, rest := splitFrontMatter(b)
+	if delim == "" {
+		delim = "---"
+		rest = "\n" + rest
+	}
+	sep := ": "
+	if delim == "+++" {
+		sep = " = "
+	}
+
+	for _, f := range fields {
+		if f[1] == "" {
+			continue
+		}
+		line := f[0] + sep + strconv.Quote(f[1])
+		found := false
+		for i, l := range lines {
+			if l == "" || l[0] == ' ' || l[0] == '\t' {
+				continue
+			}
+			k := strings.TrimSpace(strings.TrimRight(l, "\r"))
+			if i := strings.IndexAny(k, ":="); i >= 0 {
+				k = strings.Trim(strings.TrimSpace(k[:i]), `"'`)
+			}
+			if strings.EqualFold(k, f[0]) {
+				lines[i] = line
+				found = true
+				break
+			}
+		}
+		if !found {
+			lines = append(lines, line)
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(delim + "\n")
+	for _, l := range lines {
+		buf.WriteString(l + "\n")
+	}
+	buf.WriteString(delim + "\n")
+	buf.WriteString(rest)
+	return buf.Bytes()
+}
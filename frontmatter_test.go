@@ -0,0 +1,152 @@
+package writeas
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseMarkdownPost(t *testing.T) {
+	date := time.Date(2020, 3, 4, 5, 6, 7, 0, time.UTC)
+	lang := "en"
+	rtl := true
+
+	tests := []struct {
+		name string
+		in   string
+		want MarkdownPost
+	}{
+		{
+			name: "no front matter",
+			in:   "Just a post.\n",
+			want: MarkdownPost{Content: "Just a post.\n"},
+		},
+		{
+			name: "yaml",
+			in: `---
+title: "Hello: \"world\""
+slug: hello-world
+date: 2020-03-04T05:06:07Z
+lang: en
+rtl: true
+font: 'sans'
+# A comment
+tags:
+  - one
+  - "two"
+collection: blog # trailing comment
+author: matt
+unknown: ignored
+---
+
+Body here.
+`,
+			want: MarkdownPost{
+				Title:      `Hello: "world"`,
+				Slug:       "hello-world",
+				Date:       &date,
+				Language:   &lang,
+				RTL:        &rtl,
+				Font:       "sans",
+				Tags:       []string{"one", "two"},
+				Collection: "blog",
+				Author:     "matt",
+				Content:    "Body here.\n",
+			},
+		},
+		{
+			name: "toml",
+			in: `+++
+title = "TOML post"
+date = 2020-03-04T05:06:07Z
+lastmod = 2020-03-04T05:06:07Z
+tags = ["a, b", 'c']
+rtl = true
++++
+Body.`,
+			want: MarkdownPost{
+				Title:   "TOML post",
+				Date:    &date,
+				Updated: &date,
+				Tags:    []string{"a, b", "c"},
+				RTL:     &rtl,
+				Content: "Body.",
+			},
+		},
+		{
+			name: "unclosed front matter",
+			in:   "---\nNot really front matter.\n",
+			want: MarkdownPost{Content: "---\nNot really front matter.\n"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseMarkdownPost([]byte(test.in))
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			if !reflect.DeepEqual(*got, test.want) {
+				t.Errorf("got %+v\nwant %+v", *got, test.want)
+			}
+		})
+	}
+
+	if _, err := ParseMarkdownPost([]byte("---\ndate: yesterday\n---\n")); err == nil {
+		t.Errorf("Expected error for invalid date")
+	}
+}
+
+func TestMarkdownPostParams(t *testing.T) {
+	created := time.Date(2020, 3, 4, 5, 6, 7, 0, time.UTC)
+	updated := created.Add(time.Hour)
+	mp := &MarkdownPost{
+		Title:   "Hello",
+		Date:    &created,
+		Updated: &updated,
+		Tags:    []string{"GoLang"},
+		Author:  "matt",
+		Content: "Body.",
+	}
+	sp := mp.Params()
+	if sp.Created != &created || sp.Updated != &updated {
+		t.Errorf("Got created %v, updated %v; want %v, %v", sp.Created, sp.Updated, created, updated)
+	}
+	if sp.Title != "Hello" || sp.Content != "Body." || sp.AuthorSlug == nil || *sp.AuthorSlug != "matt" {
+		t.Errorf("Unexpected params: %+v", sp)
+	}
+	if len(sp.Categories) != 1 || sp.Categories[0].Slug != "go-lang" {
+		t.Errorf("Got categories %+v", sp.Categories)
+	}
+}
+
+func TestSetFrontMatter(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "replace and add",
+			in:   "---\ntitle: Post\nid: old\ntags:\n  - id\n---\n\nBody.\n",
+			want: "---\ntitle: Post\nid: \"new\"\ntags:\n  - id\nslug: \"post\"\n---\n\nBody.\n",
+		},
+		{
+			name: "toml",
+			in:   "+++\ntitle = \"Post\"\n+++\nBody.",
+			want: "+++\ntitle = \"Post\"\nid = \"new\"\nslug = \"post\"\n+++\nBody.",
+		},
+		{
+			name: "no front matter",
+			in:   "Body.\n",
+			want: "---\nid: \"new\"\nslug: \"post\"\n---\n\nBody.\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := string(setFrontMatter([]byte(test.in), [][2]string{{"id", "new"}, {"token", ""}, {"slug", "post"}}))
+			if got != test.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
@@ -1,8 +1,20 @@
 module github.com/writeas/go-writeas/v2
 
-go 1.9
+go 1.20
 
 require (
+	github.com/gosimple/slug v1.9.0
+	github.com/microcosm-cc/bluemonday v1.0.27
+	github.com/russross/blackfriday v1.6.0
+	github.com/writeas/go-strip-markdown/v2 v2.1.1
 	github.com/writeas/impart v1.1.0
+	golang.org/x/crypto v0.24.0
 	h12.io/socks v1.0.3
 )
+
+require (
+	github.com/aymerick/douceur v0.2.0 // indirect
+	github.com/gorilla/css v1.0.1 // indirect
+	github.com/rainycape/unidecode v0.0.0-20150907023854-cb7f23ec59be // indirect
+	golang.org/x/net v0.26.0 // indirect
+)
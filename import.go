@@ -0,0 +1,213 @@
+package writeas
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sidecarExt replaces a post file's extension to name its sidecar file.
+const sidecarExt = ".writeas.json"
+
+// ImportOptions configures how ImportMarkdown publishes posts.
+type ImportOptions struct {
+	// Collection is the alias of the collection to publish posts to when
+	// their front matter doesn't name one. If empty, such posts are
+	// published as drafts, or anonymously if the Client isn't logged in.
+	Collection string
+
+	// Sidecar saves each published post's ID, token and slug in a
+	// separate file next to it, named like post.writeas.json for post.md,
+	// instead of in the post's front matter. Use this to keep post tokens
+	// out of version control.
+	Sidecar bool
+}
+
+// ImportResult is the outcome of importing a single Markdown file with
+// ImportMarkdown.
+type ImportResult struct {
+	// Path is the file the post was read from.
+	Path string
+	// Post is the published post, or nil if importing it failed.
+	Post *Post
+	// Created is whether the post was newly created, rather than updated.
+	Created bool
+	Err     error
+}
+
+// postRef identifies a published post, as saved in a sidecar file.
+type postRef struct {
+	ID    string `json:"id"`
+	Token string `json:"token,omitempty"`
+	Slug  string `json:"slug,omitempty"`
+}
+
+// ImportMarkdown publishes every Markdown file (.md or .markdown) in dir and
+// its subdirectories, mapping each one's front matter onto PostParams as
+// described by ParseMarkdownPost and MarkdownPost.Params.
+//
+// Once a post is created, its ID, slug and any token are written back to its
+// file's front matter, or a sidecar file if opts.Sidecar is set, so that
+// importing the same file again updates the post instead of creating a
+// duplicate.
+//
+// It returns one result per file, in lexical order, along with the first
+// error that occurred, if any. A failed file doesn't stop the import.
+func (c *Client) ImportMarkdown(ctx context.Context, dir string, opts *ImportOptions) ([]ImportResult, error) {
+	if opts == nil {
+		opts = &ImportOptions{}
+	}
+
+	var paths []string
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() && isMarkdownFile(path) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ImportResult, len(paths))
+	var firstErr error
+	for i, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return results[:i], err
+		}
+		results[i] = c.importMarkdownFile(ctx, path, opts)
+		if results[i].Err != nil && firstErr == nil {
+			firstErr = results[i].Err
+		}
+	}
+	return results, firstErr
+}
+
+func (c *Client) importMarkdownFile(ctx context.Context, path string, opts *ImportOptions) ImportResult {
+	res := ImportResult{Path: path}
+	fail := func(err error) ImportResult {
+		res.Err = &ImportError{Path: path, Err: err}
+		return res
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fail(err)
+	}
+	mp, err := ParseMarkdownPost(b)
+	if err != nil {
+		return fail(err)
+	}
+	if opts.Sidecar {
+		ref, err := readSidecar(path)
+		if err != nil {
+			return fail(err)
+		}
+		if ref.ID != "" {
+			mp.ID, mp.Token, mp.Slug = ref.ID, ref.Token, ref.Slug
+		}
+	}
+	if mp.Collection == "" {
+		mp.Collection = opts.Collection
+	}
+
+	sp := mp.Params()
+	if mp.ID == "" {
+		res.Post, err = c.CreatePostContext(ctx, sp)
+		res.Created = true
+	} else {
+		res.Post, err = c.UpdatePostContext(ctx, mp.ID, mp.Token, sp)
+	}
+	if err != nil {
+		if res.Post == nil {
+			return fail(err)
+		}
+		// The post was created, so still save its ID and token below
+		res.Err = &ImportError{Path: path, Err: err}
+	}
+
+	ref := postRef{ID: res.Post.ID, Token: mp.Token, Slug: res.Post.Slug}
+	if res.Post.Token != "" {
+		ref.Token = res.Post.Token
+	}
+	if ref.ID == mp.ID && ref.Token == mp.Token && ref.Slug == mp.Slug {
+		return res
+	}
+	if opts.Sidecar {
+		err = writeSidecar(path, ref)
+	} else {
+		err = writePostRef(path, b, ref)
+	}
+	if err != nil && res.Err == nil {
+		return fail(err)
+	}
+	return res
+}
+
+// ImportError records a failure to import a particular file.
+type ImportError struct {
+	Path string
+	Err  error
+}
+
+func (e *ImportError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *ImportError) Unwrap() error {
+	return e.Err
+}
+
+func isMarkdownFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".md" || ext == ".markdown"
+}
+
+func sidecarPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + sidecarExt
+}
+
+func readSidecar(path string) (postRef, error) {
+	ref := postRef{}
+	b, err := ioutil.ReadFile(sidecarPath(path))
+	if os.IsNotExist(err) {
+		return ref, nil
+	} else if err != nil {
+		return ref, err
+	}
+	err = json.Unmarshal(b, &ref)
+	return ref, err
+}
+
+func writeSidecar(path string, ref postRef) error {
+	b, err := json.MarshalIndent(ref, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(sidecarPath(path), append(b, '\n'))
+}
+
+// writePostRef saves ref in the front matter of the post file at path,
+// whose current contents are b.
+func writePostRef(path string, b []byte, ref postRef) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	b = setFrontMatter(b, [][2]string{
+		{"id", ref.ID},
+		{"token", ref.Token},
+		{"slug", ref.Slug},
+	})
+	if err := writeFileAtomic(path, b); err != nil {
+		return err
+	}
+	return os.Chmod(path, fi.Mode().Perm())
+}
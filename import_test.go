@@ -0,0 +1,134 @@
+package writeas
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "writeas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		fn := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(fn), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(fn, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestImportMarkdown(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	c := NewClientWith(Config{URL: srv.APIURL()})
+	if _, err := c.LogIn("demo", "demo"); err != nil {
+		t.Fatalf("Unable to log in: %v", err)
+	}
+
+	dir := writeTestFiles(t, map[string]string{
+		"a.md":           "---\ntitle: Imported\nslug: custom-slug\ndate: 2020-01-02\nlang: fr\n---\n\nBonjour.\n",
+		"sub/b.markdown": "+++\ntitle = \"Second\"\n+++\nAnother post.\n",
+		"notes.txt":      "Not a post.",
+	})
+	defer os.RemoveAll(dir)
+
+	res, err := c.ImportMarkdown(context.Background(), dir, &ImportOptions{Collection: "tester"})
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if len(res) != 2 {
+		t.Fatalf("Got %d results, want 2", len(res))
+	}
+	for _, r := range res {
+		if !r.Created || r.Post == nil {
+			t.Errorf("Expected %s to be created, got %+v", r.Path, r)
+		}
+	}
+	p := res[0].Post
+	if p.Slug != "custom-slug" || p.Language == nil || *p.Language != "fr" || p.Created.Year() != 2020 {
+		t.Errorf("Front matter not applied: %+v", p)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "a.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `id: "`+p.ID+`"`) || !strings.HasSuffix(string(b), "---\n\nBonjour.\n") {
+		t.Errorf("ID not written back correctly:\n%s", b)
+	}
+
+	// Importing again updates instead of duplicating
+	ioutil.WriteFile(filepath.Join(dir, "a.md"), []byte(strings.Replace(string(b), "Bonjour.", "Bonjour encore.", 1)), 0644)
+	res, err = c.ImportMarkdown(context.Background(), dir, &ImportOptions{Collection: "tester"})
+	if err != nil {
+		t.Fatalf("Second import failed: %v", err)
+	}
+	if res[0].Created || res[0].Post.ID != p.ID || res[0].Post.Content != "Bonjour encore.\n" {
+		t.Errorf("Expected %s to be updated, got %+v", p.ID, res[0])
+	}
+	posts, err := c.GetCollectionPosts("tester")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*posts) != 4 {
+		t.Errorf("Collection has %d posts after importing twice, want 4", len(*posts))
+	}
+}
+
+func TestImportMarkdownSidecar(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	c := NewClientWith(Config{URL: srv.APIURL()})
+
+	orig := "---\ntitle: Anonymous\n---\n\nNo account needed.\n"
+	dir := writeTestFiles(t, map[string]string{"post.md": orig})
+	defer os.RemoveAll(dir)
+
+	opts := &ImportOptions{Sidecar: true}
+	res, err := c.ImportMarkdown(context.Background(), dir, opts)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	p := res[0].Post
+
+	if b, _ := ioutil.ReadFile(filepath.Join(dir, "post.md")); string(b) != orig {
+		t.Errorf("Post file changed with Sidecar set:\n%s", b)
+	}
+	ref, err := readSidecar(filepath.Join(dir, "post.md"))
+	if err != nil {
+		t.Fatalf("Unable to read sidecar: %v", err)
+	}
+	if ref.ID != p.ID || ref.Token == "" {
+		t.Errorf("Sidecar = %+v, want ID %s with token", ref, p.ID)
+	}
+
+	res, err = c.ImportMarkdown(context.Background(), dir, opts)
+	if err != nil {
+		t.Fatalf("Second import failed: %v", err)
+	}
+	if res[0].Created || res[0].Post.ID != p.ID {
+		t.Errorf("Expected %s to be updated using the sidecar token, got %+v", p.ID, res[0])
+	}
+
+	t.Run("bad front matter", func(t *testing.T) {
+		dir := writeTestFiles(t, map[string]string{"bad.md": "---\nrtl: maybe\n---\nBody.\n"})
+		defer os.RemoveAll(dir)
+		res, err := c.ImportMarkdown(context.Background(), dir, nil)
+		if err == nil || res[0].Err == nil {
+			t.Fatalf("Expected error for invalid front matter")
+		}
+		if ie, ok := res[0].Err.(*ImportError); !ok || !strings.HasSuffix(ie.Path, "bad.md") {
+			t.Errorf("Expected ImportError for bad.md, got: %v", res[0].Err)
+		}
+	})
+}
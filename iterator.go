@@ -0,0 +1,165 @@
+package writeas
+
+import "context"
+
+// PostIterator lazily walks through every page of a list of posts, fetching
+// each page only when it's needed.
+//
+//	it := c.CollectionPosts(ctx, "blog")
+//	for it.Next() {
+//		p := it.Post()
+//		// ...
+//	}
+//	if err := it.Err(); err != nil {
+//		// handle
+//	}
+type PostIterator struct {
+	// Prefetch makes the iterator fetch the next page in the background
+	// while the current one is being consumed. It must be set before the
+	// first call to Next.
+	Prefetch bool
+
+	ctx   context.Context
+	fetch func(ctx context.Context, page int) ([]Post, int, error)
+
+	page    int
+	posts   []Post
+	i       int
+	total   int
+	seen    map[string]bool
+	fresh   int
+	pending chan postPage
+	done    bool
+	err     error
+}
+
+// postPage is the result of fetching a single page of posts.
+type postPage struct {
+	posts []Post
+	total int
+	err   error
+}
+
+// CollectionPosts returns a PostIterator over all of a collection's posts,
+// fetching pages with GetCollectionPostsPage as they're needed.
+func (c *Client) CollectionPosts(ctx context.Context, alias string) *PostIterator {
+	return newPostIterator(ctx, func(ctx context.Context, page int) ([]Post, int, error) {
+		coll, err := c.GetCollectionPostsPageContext(ctx, alias, page)
+		if err != nil {
+			return nil, 0, err
+		}
+		return *coll.Posts, coll.TotalPosts, nil
+	})
+}
+
+// UserPosts returns a PostIterator over all of the authenticated user's
+// posts. The API doesn't page /me/posts, so they're all fetched at once with
+// GetUserPosts, when Next is first called.
+func (c *Client) UserPosts(ctx context.Context) *PostIterator {
+	return newPostIterator(ctx, func(ctx context.Context, page int) ([]Post, int, error) {
+		if page > 1 {
+			return nil, 0, nil
+		}
+		p, err := c.GetUserPostsContext(ctx)
+		if err != nil {
+			return nil, 0, err
+		}
+		return *p, len(*p), nil
+	})
+}
+
+func newPostIterator(ctx context.Context, fetch func(context.Context, int) ([]Post, int, error)) *PostIterator {
+	return &PostIterator{
+		ctx:   ctx,
+		fetch: fetch,
+		i:     -1,
+		total: -1,
+		seen:  map[string]bool{},
+	}
+}
+
+// Next advances to the next post, fetching the next page if necessary. It
+// returns false once there are no more posts, or an error occurs.
+//
+// Posts that show up again on a later page, as happens when posts are
+// published or deleted during the walk, are skipped. Iteration ends once a
+// page brings no new posts, or all of the posts the server reported have
+// been seen.
+func (it *PostIterator) Next() bool {
+	if it.done {
+		return false
+	}
+	for {
+		for it.i+1 < len(it.posts) {
+			it.i++
+			p := it.posts[it.i]
+			if it.seen[p.ID] {
+				continue
+			}
+			it.seen[p.ID] = true
+			it.fresh++
+			return true
+		}
+		// Stop once a page has nothing new, which also covers servers that
+		// don't support paging and keep returning the same posts
+		if it.page > 0 && (it.fresh == 0 || (it.total >= 0 && len(it.seen) >= it.total)) {
+			it.done = true
+			return false
+		}
+
+		res := it.nextPage()
+		if res.err != nil {
+			it.err = res.err
+			it.done = true
+			return false
+		}
+		it.posts, it.i, it.total, it.fresh = res.posts, -1, res.total, 0
+		if it.Prefetch && len(it.posts) > 0 && (it.total < 0 || len(it.seen)+len(it.posts) < it.total) {
+			it.startFetch(it.page + 1)
+		}
+	}
+}
+
+// nextPage returns the next page of posts, waiting for it if it's already
+// being prefetched.
+func (it *PostIterator) nextPage() postPage {
+	it.page++
+	if it.pending == nil {
+		it.startFetch(it.page)
+	}
+	res := <-it.pending
+	it.pending = nil
+	return res
+}
+
+// startFetch starts fetching the given page in the background.
+func (it *PostIterator) startFetch(page int) {
+	// Buffered, so the goroutine can always finish, even if the iterator is
+	// abandoned
+	ch := make(chan postPage, 1)
+	it.pending = ch
+	go func() {
+		posts, total, err := it.fetch(it.ctx, page)
+		ch <- postPage{posts: posts, total: total, err: err}
+	}()
+}
+
+// Post returns the current post. It's only valid after a call to Next
+// returns true.
+func (it *PostIterator) Post() *Post {
+	if it.i < 0 || it.i >= len(it.posts) {
+		return nil
+	}
+	return &it.posts[it.i]
+}
+
+// Total returns the total number of posts, if the server reported it, or -1
+// otherwise. It's only known once the first page has been fetched.
+func (it *PostIterator) Total() int {
+	return it.total
+}
+
+// Err returns the error, if any, that stopped the iteration.
+func (it *PostIterator) Err() error {
+	return it.err
+}
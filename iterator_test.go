@@ -0,0 +1,175 @@
+package writeas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/writeas/go-writeas/v2/writeastest"
+)
+
+func newPagedServer(n int) *writeastest.Server {
+	srv := writeastest.NewServer()
+	srv.AddUser("demo", "demo")
+	srv.AddCollection("demo", "paged", "Paged")
+	for i := 0; i < n; i++ {
+		srv.AddCollectionPost("paged", fmt.Sprintf("Post %d", i), "Hello")
+	}
+	return srv
+}
+
+func TestGetCollectionPostsPage(t *testing.T) {
+	srv := newPagedServer(writeastest.PostsPerPage + 5)
+	defer srv.Close()
+	c := NewClientWith(Config{URL: srv.APIURL()})
+
+	coll, err := c.GetCollectionPostsPage("paged", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*coll.Posts) != 5 {
+		t.Errorf("Got %d posts on page 2, want 5", len(*coll.Posts))
+	}
+	if coll.TotalPosts != writeastest.PostsPerPage+5 {
+		t.Errorf("Got %d total posts, want %d", coll.TotalPosts, writeastest.PostsPerPage+5)
+	}
+
+	coll, err = c.GetCollectionPostsPage("paged", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*coll.Posts) != 0 {
+		t.Errorf("Got %d posts past the last page, want 0", len(*coll.Posts))
+	}
+
+	if _, err := c.GetCollectionPostsPage("paged", 0); err == nil {
+		t.Errorf("Expected error for page 0")
+	}
+}
+
+func TestCollectionPostsIterator(t *testing.T) {
+	for _, prefetch := range []bool{false, true} {
+		t.Run(fmt.Sprintf("prefetch=%t", prefetch), func(t *testing.T) {
+			n := 3*writeastest.PostsPerPage + 4
+			srv := newPagedServer(n)
+			defer srv.Close()
+			c := NewClientWith(Config{URL: srv.APIURL()})
+
+			it := c.CollectionPosts(context.Background(), "paged")
+			it.Prefetch = prefetch
+			seen := map[string]bool{}
+			for it.Next() {
+				p := it.Post()
+				if seen[p.ID] {
+					t.Fatalf("Post %s returned twice", p.ID)
+				}
+				seen[p.ID] = true
+			}
+			if err := it.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if len(seen) != n {
+				t.Errorf("Iterated over %d posts, want %d", len(seen), n)
+			}
+			if it.Total() != n {
+				t.Errorf("Total() = %d, want %d", it.Total(), n)
+			}
+			if it.Next() {
+				t.Errorf("Next() returned true after iteration finished")
+			}
+		})
+	}
+}
+
+func TestCollectionPostsIteratorError(t *testing.T) {
+	srv := newPagedServer(0)
+	defer srv.Close()
+	c := NewClientWith(Config{URL: srv.APIURL()})
+
+	it := c.CollectionPosts(context.Background(), "nope")
+	if it.Next() {
+		t.Fatal("Expected no posts")
+	}
+	if !errors.Is(it.Err(), ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got: %v", it.Err())
+	}
+}
+
+func TestUserPostsIterator(t *testing.T) {
+	srv := writeastest.NewServer()
+	defer srv.Close()
+	srv.AddUser("demo", "demo")
+	c := NewClientWith(Config{URL: srv.APIURL()})
+	if _, err := c.LogIn("demo", "demo"); err != nil {
+		t.Fatal(err)
+	}
+
+	n := writeastest.PostsPerPage + 1
+	for i := 0; i < n; i++ {
+		if _, err := c.CreatePost(&PostParams{Content: fmt.Sprintf("Post %d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	count := 0
+	it := c.UserPosts(context.Background())
+	for it.Next() {
+		count++
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if count != n {
+		t.Errorf("Iterated over %d posts, want %d", count, n)
+	}
+}
+
+func TestUserPostsUnpaged(t *testing.T) {
+	// The API returns all of a user's posts at once, ignoring any page
+	var reqs int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&reqs, 1)
+		w.Write([]byte(`{"code":200,"data":[{"id":"a"},{"id":"b"}]}`))
+	}))
+	defer srv.Close()
+	c := NewClientWith(Config{URL: srv.URL, Token: "token"})
+
+	count := 0
+	it := c.UserPosts(context.Background())
+	for it.Next() {
+		count++
+	}
+	if count != 2 || it.Err() != nil {
+		t.Errorf("Got %d posts and error %v, want 2 posts", count, it.Err())
+	}
+	if n := atomic.LoadInt32(&reqs); n != 1 {
+		t.Errorf("Made %d requests, want 1", n)
+	}
+}
+
+func TestPostIteratorShiftedPages(t *testing.T) {
+	// A post published during the walk pushes "b" onto the second page too
+	pages := [][]Post{
+		{{ID: "a"}, {ID: "b"}},
+		{{ID: "b"}, {ID: "c"}},
+		{{ID: "c"}, {ID: "d"}},
+	}
+	it := newPostIterator(context.Background(), func(ctx context.Context, page int) ([]Post, int, error) {
+		if page > len(pages) {
+			return nil, -1, nil
+		}
+		return pages[page-1], -1, nil
+	})
+
+	var ids []string
+	for it.Next() {
+		ids = append(ids, it.Post().ID)
+	}
+	if fmt.Sprint(ids) != "[a b c d]" || it.Err() != nil {
+		t.Errorf("Got posts %v and error %v, want [a b c d]", ids, it.Err())
+	}
+}
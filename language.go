@@ -0,0 +1,305 @@
+package writeas
+
+import (
+	"strings"
+	"unicode"
+)
+
+// DefaultMinLanguageConfidence is the confidence a LanguageDetector needs in
+// its guess to fill in a post's language, unless MinConfidence is set.
+const DefaultMinLanguageConfidence = 0.5
+
+// minDetectLetters is the number of letters needed to be fully confident in
+// a guess based on a post's script.
+const minDetectLetters = 20
+
+// LanguageGuess is a guess at the language a post is written in.
+type LanguageGuess struct {
+	// Language is an ISO 639-1 code, or empty if no guess could be made.
+	Language string
+	// RTL is whether the language is written right-to-left.
+	RTL bool
+	// Confidence ranges from 0, for no idea, to 1.
+	Confidence float64
+}
+
+// LanguageDetector guesses the language and direction of posts created or
+// updated with a Client, when PostParams.Language and PostParams.IsRTL aren't
+// set. Enable it with Config.LanguageDetector or Client.SetLanguageDetector,
+// and skip it for a single post with PostParams.SkipLanguageDetection.
+type LanguageDetector struct {
+	// MinConfidence is the lowest confidence a guess can have to be used.
+	// Defaults to DefaultMinLanguageConfidence.
+	MinConfidence float64
+}
+
+// Apply fills in sp.Language and sp.IsRTL, whichever are nil, and returns
+// whether anything was filled in. If sp.Language is set, the direction is
+// taken from it. Otherwise the language of sp.Title and sp.Content is
+// guessed, and nothing is filled in if the guess isn't confident enough, or
+// doesn't agree with an IsRTL that's already set.
+func (d *LanguageDetector) Apply(sp *PostParams) bool {
+	if sp.Language != nil {
+		if sp.IsRTL != nil {
+			return false
+		}
+		rtl := isRTL(*sp.Language)
+		sp.IsRTL = &rtl
+		return true
+	}
+	min := d.MinConfidence
+	if min <= 0 {
+		min = DefaultMinLanguageConfidence
+	}
+	g := DetectLanguage(sp.Title + "\n\n" + sp.Content)
+	if g.Language == "" || g.Confidence < min {
+		return false
+	}
+	if sp.IsRTL != nil && *sp.IsRTL != g.RTL {
+		return false
+	}
+	lang := g.Language
+	sp.Language = &lang
+	if sp.IsRTL == nil {
+		rtl := g.RTL
+		sp.IsRTL = &rtl
+	}
+	return true
+}
+
+// rtlLanguages are the languages written right-to-left.
+var rtlLanguages = map[string]bool{
+	"ar":  true,
+	"ckb": true,
+	"dv":  true,
+	"fa":  true,
+	"he":  true,
+	"ps":  true,
+	"sd":  true,
+	"ug":  true,
+	"ur":  true,
+	"yi":  true,
+}
+
+// isRTL returns whether the language with the given code, which may include
+// a region like "ar-EG", is written right-to-left.
+func isRTL(lang string) bool {
+	lang = strings.ToLower(lang)
+	if i := strings.IndexAny(lang, "-_"); i != -1 {
+		lang = lang[:i]
+	}
+	return rtlLanguages[lang]
+}
+
+// scriptLanguages are the languages detected only by the script they're
+// written in.
+var scriptLanguages = []struct {
+	script *unicode.RangeTable
+	lang   string
+}{
+	{unicode.Hebrew, "he"},
+	{unicode.Greek, "el"},
+	{unicode.Hangul, "ko"},
+	{unicode.Thai, "th"},
+	{unicode.Devanagari, "hi"},
+	{unicode.Bengali, "bn"},
+	{unicode.Tamil, "ta"},
+	{unicode.Armenian, "hy"},
+	{unicode.Georgian, "ka"},
+}
+
+// detectScripts are the scripts counted by DetectLanguage, in the order
+// they're preferred when text has as many letters in one as another.
+var detectScripts = func() []*unicode.RangeTable {
+	s := []*unicode.RangeTable{unicode.Latin, unicode.Arabic, unicode.Cyrillic, unicode.Han, unicode.Hiragana, unicode.Katakana}
+	for _, sl := range scriptLanguages {
+		s = append(s, sl.script)
+	}
+	return s
+}()
+
+// stopWords are common words in languages written in the Latin script, used
+// to tell them apart.
+var stopWords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "in", "that", "it", "was", "for", "with", "you", "this", "are", "have", "be", "not", "on", "but", "what"},
+	"fr": {"le", "la", "les", "des", "est", "et", "une", "un", "du", "que", "qui", "pas", "pour", "dans", "sur", "avec", "je", "ce", "il", "ne", "sont", "au"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "ich", "zu", "den", "mit", "von", "sich", "auf", "dem", "auch", "es", "sie", "wir"},
+	"es": {"el", "los", "las", "que", "y", "es", "una", "por", "con", "para", "del", "se", "no", "como", "pero", "más", "su", "al", "lo", "muy"},
+	"it": {"il", "che", "di", "è", "non", "e", "per", "una", "sono", "gli", "della", "con", "un", "del", "le", "mi", "ma", "anche", "questo", "come"},
+	"pt": {"o", "os", "que", "não", "uma", "do", "da", "em", "para", "com", "é", "um", "se", "dos", "as", "mas", "por", "mais", "ao", "você"},
+	"nl": {"de", "het", "een", "en", "van", "is", "dat", "niet", "ik", "je", "op", "te", "zijn", "met", "voor", "er", "maar", "ook", "wat", "hij"},
+	"sv": {"och", "att", "det", "är", "som", "en", "på", "för", "med", "har", "inte", "jag", "till", "av", "om", "ett", "men", "de", "vi", "den"},
+	"pl": {"się", "nie", "i", "w", "na", "jest", "że", "z", "do", "to", "jak", "ale", "co", "tak", "czy", "od", "po", "przez", "dla", "jestem"},
+	"tr": {"ve", "bir", "bu", "da", "de", "için", "ile", "çok", "ne", "ama", "gibi", "daha", "olarak", "var", "değil", "ben", "sen", "o", "mi", "her"},
+	"id": {"yang", "dan", "di", "ini", "itu", "dengan", "untuk", "tidak", "dari", "dalam", "akan", "pada", "juga", "saya", "ada", "ke", "kami", "bisa", "karena", "adalah"},
+}
+
+// stopWordLangs maps each of the stopWords to the languages it's common in.
+var stopWordLangs = func() map[string][]string {
+	m := map[string][]string{}
+	for lang, words := range stopWords {
+		for _, w := range words {
+			m[w] = append(m[w], lang)
+		}
+	}
+	return m
+}()
+
+// DetectLanguage guesses the language of the given text, which may be
+// Markdown. Languages are told apart first by the script they're written in,
+// then by letters specific to a language, like Persian and Urdu ones in the
+// Arabic script, and finally by common words, for languages written in the
+// Latin script.
+func DetectLanguage(text string) LanguageGuess {
+	text = urlReg.ReplaceAllString(plainText(text), " ")
+
+	scripts := map[*unicode.RangeTable]int{}
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for _, s := range detectScripts {
+			if unicode.Is(s, r) {
+				scripts[s]++
+			}
+		}
+	}
+	if letters == 0 {
+		return LanguageGuess{}
+	}
+
+	// Find the main script, counting Chinese characters and Japanese kana
+	// together
+	cjk := scripts[unicode.Han] + scripts[unicode.Hiragana] + scripts[unicode.Katakana]
+	main, count := unicode.Latin, scripts[unicode.Latin]
+	for _, s := range detectScripts {
+		n := scripts[s]
+		if s == unicode.Hiragana || s == unicode.Katakana {
+			continue
+		}
+		if s == unicode.Han {
+			n = cjk
+		}
+		if n > count {
+			main, count = s, n
+		}
+	}
+
+	share := float64(count) / float64(letters)
+	if letters < minDetectLetters {
+		share *= float64(letters) / minDetectLetters
+	}
+
+	var lang string
+	certainty := 1.0
+	switch main {
+	case unicode.Latin:
+		lang, certainty = latinLanguage(text)
+	case unicode.Arabic:
+		lang, certainty = arabicLanguage(text)
+	case unicode.Cyrillic:
+		lang, certainty = cyrillicLanguage(text)
+	case unicode.Han:
+		lang = "zh"
+		if kana := cjk - scripts[unicode.Han]; float64(kana) >= 0.05*float64(cjk) {
+			lang = "ja"
+		}
+	default:
+		for _, sl := range scriptLanguages {
+			if sl.script == main {
+				lang = sl.lang
+			}
+		}
+	}
+	if lang == "" {
+		return LanguageGuess{}
+	}
+	return LanguageGuess{
+		Language:   lang,
+		RTL:        rtlLanguages[lang],
+		Confidence: share * certainty,
+	}
+}
+
+// latinLanguage guesses which language written in the Latin script text is
+// in, by its common words, and how certain that guess is.
+func latinLanguage(text string) (string, float64) {
+	hits := map[string]int{}
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	}) {
+		for _, lang := range stopWordLangs[w] {
+			hits[lang]++
+		}
+	}
+
+	best, bestN, secondN := "", 0, 0
+	for lang, n := range hits {
+		if n > bestN || n == bestN && lang < best {
+			best, bestN, secondN = lang, n, bestN
+		} else if n > secondN {
+			secondN = n
+		}
+	}
+	if bestN == 0 {
+		return "", 0
+	}
+	// Be less certain when there are few common words to go on, or another
+	// language is close behind
+	margin := float64(bestN-secondN) / float64(bestN)
+	coverage := float64(bestN) / 4
+	if coverage > 1 {
+		coverage = 1
+	}
+	return best, margin * coverage
+}
+
+// arabicLanguage guesses whether text in the Arabic script is in Arabic,
+// Persian or Urdu, by letters specific to each, and how certain that guess
+// is.
+func arabicLanguage(text string) (string, float64) {
+	arabic := countRunes(text, "ةيكىإأؤ")
+	persian := countRunes(text, "پچژگکی")
+	urdu := countRunes(text, "ٹڈڑںےہھ")
+	switch {
+	case urdu > 0 && urdu >= arabic:
+		return "ur", 1
+	case persian > arabic:
+		return "fa", 1
+	case arabic > 0:
+		return "ar", 1
+	}
+	return "ar", 0.5
+}
+
+// cyrillicLanguage guesses which language written in the Cyrillic script
+// text is in, by letters specific to each, and how certain that guess is.
+func cyrillicLanguage(text string) (string, float64) {
+	text = strings.ToLower(text)
+	russian := countRunes(text, "ыэё")
+	switch {
+	case countRunes(text, "ђћџљњј") > 0:
+		return "sr", 1
+	case countRunes(text, "іїєґ") > russian:
+		return "uk", 1
+	case russian > 0:
+		return "ru", 1
+	case countRunes(text, "ъ") > 0:
+		return "bg", 1
+	}
+	return "ru", 0.5
+}
+
+// countRunes returns the number of times any of the runes in chars appear in
+// s.
+func countRunes(s, chars string) int {
+	n := 0
+	for _, r := range s {
+		if strings.ContainsRune(chars, r) {
+			n++
+		}
+	}
+	return n
+}
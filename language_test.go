@@ -0,0 +1,177 @@
+package writeas
+
+import (
+	"testing"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		lang string
+		rtl  bool
+	}{
+		{"english", "This is a post about the things that I have been writing, and it is not short.", "en", false},
+		{"french", "C'est un article sur les choses que je ne peux pas écrire dans le journal.", "fr", false},
+		{"german", "Das ist ein Beitrag, und ich habe ihn nicht mit der Hand auf dem Papier geschrieben.", "de", false},
+		{"spanish", "Este es un artículo sobre las cosas que no se pueden escribir con el lápiz para los niños.", "es", false},
+		{"arabic", "هذه مقالة قصيرة عن الكتابة باللغة العربية في المدينة الكبيرة", "ar", true},
+		{"persian", "این یک نوشته کوتاه درباره نوشتن به زبان فارسی است که پیش از این گفتم", "fa", true},
+		{"urdu", "یہ اردو زبان میں لکھنے کے بارے میں ایک مختصر تحریر ہے", "ur", true},
+		{"hebrew", "זהו פוסט קצר על כתיבה בשפה העברית בעיר הגדולה", "he", true},
+		{"russian", "Это короткий пост о том, как мы пишем на русском языке.", "ru", false},
+		{"ukrainian", "Це короткий допис про те, як ми пишемо українською мовою і їхні історії.", "uk", false},
+		{"japanese", "これは日本語で書かれた短い投稿です。", "ja", false},
+		{"chinese", "这是一篇用中文写的关于写作的短文章。", "zh", false},
+		{"korean", "이것은 한국어로 쓴 짧은 게시물입니다.", "ko", false},
+		{"greek", "Αυτή είναι μια σύντομη ανάρτηση στα ελληνικά.", "el", false},
+		{"markdown and urls", "# Title\n\nSee https://example.com/the/and/of/is/that for **this** and the rest of it.", "en", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g := DetectLanguage(test.text)
+			if g.Language != test.lang || g.RTL != test.rtl {
+				t.Errorf("Got %+v, want %s (RTL %v)", g, test.lang, test.rtl)
+			}
+			if g.Confidence < DefaultMinLanguageConfidence {
+				t.Errorf("Confidence %v below default threshold", g.Confidence)
+			}
+		})
+	}
+
+	for _, text := range []string{"", "12345 !!!", "OK", "Hola"} {
+		if g := DetectLanguage(text); g.Confidence >= DefaultMinLanguageConfidence {
+			t.Errorf("DetectLanguage(%q) = %+v, expected low confidence", text, g)
+		}
+	}
+}
+
+func TestDetectLanguageShortText(t *testing.T) {
+	// These languages share common words, like "de" and "que", so short
+	// texts should either be guessed right or not confidently at all
+	tests := []struct {
+		text, lang string
+	}{
+		{"O gato e o cão.", "pt"},
+		{"A casa de que falei.", "pt"},
+		{"Uma casa de madeira de pinho.", "pt"},
+		{"Acho que de verdade é assim.", "pt"},
+		{"Ela diz que não.", "pt"},
+		{"La casa de que hablé.", "es"},
+		{"Una casa de madera de pino.", "es"},
+		{"Vivo en la casa en el campo.", "es"},
+		{"Creo que de verdad es así.", "es"},
+		{"Es una casa de campo.", "es"},
+		{"De kat en de hond.", "nl"},
+		{"Het huis van de man.", "nl"},
+		{"Ik woon in de stad en de zon schijnt.", "nl"},
+		{"Die Katze und der Hund.", "de"},
+		{"Das Haus von dem Mann.", "de"},
+		{"Wir sind in Berlin.", "de"},
+		{"Es ist ein schöner Tag.", "de"},
+	}
+	for _, test := range tests {
+		g := DetectLanguage(test.text)
+		if g.Language != test.lang && g.Confidence >= DefaultMinLanguageConfidence {
+			t.Errorf("DetectLanguage(%q) = %+v, confidently wrong (want %s)", test.text, g, test.lang)
+		}
+	}
+}
+
+func TestDetectLanguageMixedScripts(t *testing.T) {
+	// As many letters in each script, so the first one in detectScripts wins
+	// every time
+	text := "Привет, κόσμος! ישראלי"
+	want := DetectLanguage(text)
+	if want.Language != "ru" {
+		t.Errorf("DetectLanguage(%q) = %+v, want ru", text, want)
+	}
+	for i := 0; i < 50; i++ {
+		if g := DetectLanguage(text); g != want {
+			t.Fatalf("DetectLanguage(%q) = %+v, then %+v", text, want, g)
+		}
+	}
+}
+
+func TestLanguageDetectorApply(t *testing.T) {
+	d := &LanguageDetector{}
+	sp := &PostParams{Title: "עברית", Content: "זהו פוסט קצר על כתיבה בשפה העברית."}
+	if !d.Apply(sp) || sp.Language == nil || *sp.Language != "he" || sp.IsRTL == nil || !*sp.IsRTL {
+		t.Errorf("Language not applied: %+v", sp)
+	}
+
+	// Fields already set are left alone
+	lang := "yi"
+	sp = &PostParams{Content: sp.Content, Language: &lang}
+	d.Apply(sp)
+	if *sp.Language != "yi" || sp.IsRTL == nil || !*sp.IsRTL {
+		t.Errorf("Expected only RTL to be filled in: %+v", sp)
+	}
+
+	// The direction comes from a language that's set, not from a guess
+	lang = "he-IL"
+	sp = &PostParams{Content: "This post is written in English, despite its language.", Language: &lang}
+	if !d.Apply(sp) || sp.IsRTL == nil || !*sp.IsRTL {
+		t.Errorf("Expected RTL from language: %+v", sp)
+	}
+
+	// A guess that doesn't agree with the direction that's set isn't used
+	rtl := true
+	sp = &PostParams{Content: "This post is written in English, despite its direction.", IsRTL: &rtl}
+	if d.Apply(sp) || sp.Language != nil || !*sp.IsRTL {
+		t.Errorf("Expected guess to be ignored: %+v", sp)
+	}
+	sp = &PostParams{Content: "זהו פוסט קצר על כתיבה בשפה העברית בעיר הגדולה", IsRTL: &rtl}
+	if !d.Apply(sp) || sp.Language == nil || *sp.Language != "he" {
+		t.Errorf("Expected language matching direction to be filled in: %+v", sp)
+	}
+
+	d = &LanguageDetector{MinConfidence: 0.99}
+	sp = &PostParams{Content: "Hello, this is short."}
+	if d.Apply(sp) || sp.Language != nil {
+		t.Errorf("Applied guess below MinConfidence: %+v", sp)
+	}
+}
+
+func TestClientLanguageDetector(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	c := NewClientWith(Config{URL: srv.APIURL(), LanguageDetector: &LanguageDetector{}})
+
+	sp := &PostParams{Content: "هذه مقالة قصيرة عن الكتابة باللغة العربية في المدينة الكبيرة"}
+	p, err := c.CreatePost(sp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Language == nil || *p.Language != "ar" || p.RTL == nil || !*p.RTL {
+		t.Errorf("Created post without detected language: %+v", p)
+	}
+	if sp.Language != nil || sp.IsRTL != nil {
+		t.Errorf("Caller's PostParams were changed: %+v", sp)
+	}
+
+	p, err = c.UpdatePost(p.ID, p.Token, &PostParams{Content: "This is now a post in English, and that is all there is to it."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *p.Language != "en" || *p.RTL {
+		t.Errorf("Updated post without detected language: %+v", p)
+	}
+
+	p, err = c.CreatePost(&PostParams{Content: "זהו פוסט קצר על כתיבה בשפה העברית בעיר הגדולה", SkipLanguageDetection: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Language != nil || p.RTL != nil {
+		t.Errorf("Language detected despite SkipLanguageDetection: %+v", p)
+	}
+
+	c.SetLanguageDetector(nil)
+	p, err = c.CreatePost(&PostParams{Content: "זהו פוסט קצר על כתיבה בשפה העברית בעיר הגדולה"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Language != nil {
+		t.Errorf("Language detected after removing detector: %+v", p)
+	}
+}
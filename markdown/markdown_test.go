@@ -0,0 +1,115 @@
+package markdown_test
+
+import (
+	"flag"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/writeas/go-writeas/v2"
+	"github.com/writeas/go-writeas/v2/markdown"
+	"github.com/writeas/go-writeas/v2/writeastest"
+)
+
+var record = flag.String("record", "", "record /markdown responses from the API at this URL, e.g. https://write.as/api")
+
+// recording holds the /markdown responses TestParity compares against.
+const recording = "testdata/markdown.json"
+
+var parityTests = []struct {
+	name          string
+	body          string
+	collectionURL string
+}{
+	{"emphasis", "This is *formatted* in __Markdown__.", ""},
+	{"smart punctuation", "\"Quotes\" and 'apostrophes' aren't dumb -- nor are dashes... (c) 1/2", ""},
+	{"hashtags", "Tagged #writing and #go_lang, but not #2020, foo#bar or `#code`.\n\n#start of a line", "https://write.as/matt/"},
+	{"hashtags without collection", "Tagged #writing.", ""},
+	{"headings", "# Hello, World!\n\n## Hello, World!\n\n#Not a heading", ""},
+	{"code fences", "```go\nfmt.Println(\"<hi>\") // #comment\n```\n\n    indented <code>\n", "https://write.as/matt/"},
+	{"footnotes", "A claim.[^1]\n\n[^1]: The source.", ""},
+	{"more marker", "The excerpt.\n\n<!--more-->\n\nThe rest.", ""},
+	{"lists and quotes", "- one\n- two\n\n1. first\n2. second\n\n> Quoted\n> text.", ""},
+	{"tables", "| Name | Count |\n|------|------:|\n| a | 1 |", ""},
+	{"links", "[A link](https://write.as), https://writefreely.org and <span onclick=\"x()\">bad</span> [js](javascript:alert(1))", ""},
+	{"unsafe html", "<script>alert('hi')</script><b style=\"color:red\">Bold</b>\n\n<iframe src=\"https://www.youtube.com/embed/abc_123?autoplay=1\" width=\"560\" onload=\"x()\"></iframe>", ""},
+	{"mentions", "Thanks, @matt@write.as!", ""},
+}
+
+// knownDifferences are the parityTests where Render is known not to match
+// the server, and why.
+var knownDifferences = map[string]string{
+	"mentions": "Render doesn't link fediverse mentions like @user@instance, which the server does when federation is enabled",
+}
+
+// TestParity checks that Render matches the /markdown responses recorded
+// from the API, apart from knownDifferences. To record them, run:
+//
+//	go test -run TestParity -record https://write.as/api
+func TestParity(t *testing.T) {
+	var rec *writeastest.Recorder
+	var c *writeas.Client
+	if *record != "" {
+		rec = writeastest.NewRecorder(recording, nil)
+		c = writeas.NewClientWith(writeas.Config{URL: *record, HTTPClient: rec.Client()})
+	} else {
+		var err error
+		rec, err = writeastest.NewReplayer(recording)
+		if os.IsNotExist(err) {
+			t.Skipf("No responses recorded in %s; record them with -record", recording)
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		c = writeas.NewClientWith(writeas.Config{HTTPClient: rec.Client()})
+	}
+
+	for _, test := range parityTests {
+		t.Run(test.name, func(t *testing.T) {
+			want, err := c.Markdown(test.body, test.collectionURL)
+			if err != nil {
+				t.Fatalf("No response for %q: %v", test.body, err)
+			}
+			got := markdown.Render(test.body, test.collectionURL)
+			reason, known := knownDifferences[test.name]
+			switch {
+			case got == want && known:
+				t.Errorf("Render now matches the server; remove %q from knownDifferences", test.name)
+			case got != want && known:
+				t.Logf("Known difference: %s", reason)
+			case got != want:
+				t.Errorf("Render(%q) =\n%q\nserver rendered\n%q", test.body, got, want)
+			}
+		})
+	}
+
+	if *record != "" {
+		if err := rec.Save(); err != nil {
+			t.Fatal(err)
+		}
+	} else if n := len(rec.Unused()); n > 0 {
+		t.Errorf("%d recorded responses weren't checked", n)
+	}
+}
+
+func TestRenderHashtags(t *testing.T) {
+	out := markdown.Render("#one foo#two #three", "/")
+	for _, tag := range []string{"one", "three"} {
+		if !strings.Contains(out, `href="/tag:`+tag+`"`) {
+			t.Errorf("#%s not linked in %q", tag, out)
+		}
+	}
+	if strings.Contains(out, "tag:two") {
+		t.Errorf("Hashtag not preceded by a space was linked in %q", out)
+	}
+}
+
+func TestSplitMore(t *testing.T) {
+	excerpt, more := markdown.SplitMore("Intro.\n\n<!--more-->\n\nRest.")
+	if excerpt != "Intro.\n\n" || !more {
+		t.Errorf("Got %q, %v", excerpt, more)
+	}
+	excerpt, more = markdown.SplitMore("No marker.")
+	if excerpt != "No marker." || more {
+		t.Errorf("Got %q, %v", excerpt, more)
+	}
+}
@@ -0,0 +1,134 @@
+package writeas
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// SendFunc sends a single API request and returns the server's response.
+type SendFunc func(*http.Request) (*http.Response, error)
+
+// Middleware wraps the sending of every request a Client makes, e.g. to log
+// it, modify it, or short-circuit it with a response of its own. It's called
+// once per attempt, so retried requests pass through it again.
+//
+// Middleware are applied in the order they're given, so the first one sees
+// the request first and the response last.
+type Middleware func(next SendFunc) SendFunc
+
+// Use adds the given Middleware to the end of the Client's middleware chain.
+func (c *Client) Use(mw ...Middleware) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Always copy, since the current slice may be shared with derived Clients
+	c.middleware = append(c.middleware[:len(c.middleware):len(c.middleware)], mw...)
+}
+
+// chain wraps the given SendFunc with the given middleware.
+func chain(mw []Middleware, send SendFunc) SendFunc {
+	for i := len(mw) - 1; i >= 0; i-- {
+		send = mw[i](send)
+	}
+	return send
+}
+
+// RequestLog describes a single request made by a Client. Any credentials
+// are redacted from it, so it's safe to write to logs.
+type RequestLog struct {
+	Method string
+	URL    string
+	Header http.Header
+
+	// Status is the response's HTTP status code, or 0 if the request failed.
+	Status   int
+	Duration time.Duration
+	Err      error
+}
+
+const redacted = "REDACTED"
+
+// LoggingMiddleware returns a Middleware that calls log with a RequestLog for
+// every request once it completes. User tokens, API keys, and post tokens
+// are redacted from the logged request.
+func LoggingMiddleware(log func(RequestLog)) Middleware {
+	return func(next SendFunc) SendFunc {
+		return func(r *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next(r)
+			l := RequestLog{
+				Method:   r.Method,
+				URL:      redactURL(r.URL),
+				Header:   redactHeader(r.Header),
+				Duration: time.Since(start),
+				Err:      err,
+			}
+			if resp != nil {
+				l.Status = resp.StatusCode
+			}
+			log(l)
+			return resp, err
+		}
+	}
+}
+
+func redactHeader(h http.Header) http.Header {
+	h = cloneHeader(h)
+	for _, k := range []string{"Authorization", "X-Api-Key"} {
+		if h.Get(k) != "" {
+			h.Set(k, redacted)
+		}
+	}
+	return h
+}
+
+func redactURL(u *url.URL) string {
+	q := u.Query()
+	if q.Get("token") == "" {
+		return u.String()
+	}
+	q.Set("token", redacted)
+	ru := *u
+	ru.RawQuery = q.Encode()
+	return ru.String()
+}
+
+func cloneHeader(h http.Header) http.Header {
+	h2 := make(http.Header, len(h))
+	for k, v := range h {
+		h2[k] = append([]string(nil), v...)
+	}
+	return h2
+}
+
+// TimingMiddleware returns a Middleware that calls observe with the duration
+// of every request once it completes, e.g. for recording metrics. status is
+// 0 if the request failed.
+func TimingMiddleware(observe func(r *http.Request, status int, d time.Duration)) Middleware {
+	return func(next SendFunc) SendFunc {
+		return func(r *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next(r)
+			status := 0
+			if resp != nil {
+				status = resp.StatusCode
+			}
+			observe(r, status, time.Since(start))
+			return resp, err
+		}
+	}
+}
+
+// HeaderMiddleware returns a Middleware that sets the given headers on every
+// request, replacing any existing values.
+func HeaderMiddleware(h http.Header) Middleware {
+	return func(next SendFunc) SendFunc {
+		return func(r *http.Request) (*http.Response, error) {
+			for k, v := range h {
+				r.Header[http.CanonicalHeaderKey(k)] = append([]string(nil), v...)
+			}
+			return next(r)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package writeas
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newEchoServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"code":200,"data":{"id":"abc","body":"` + r.Header.Get("X-Test") + `"}}`))
+	}))
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	srv := newEchoServer()
+	defer srv.Close()
+
+	var calls []string
+	mw := func(name string) Middleware {
+		return func(next SendFunc) SendFunc {
+			return func(r *http.Request) (*http.Response, error) {
+				calls = append(calls, name+" before")
+				resp, err := next(r)
+				calls = append(calls, name+" after")
+				return resp, err
+			}
+		}
+	}
+	c := NewClientWith(Config{URL: srv.URL, Middleware: []Middleware{mw("a")}})
+	c.Use(mw("b"))
+
+	if _, err := c.GetPost("abc"); err != nil {
+		t.Fatal(err)
+	}
+	want := "a before,b before,b after,a after"
+	if got := strings.Join(calls, ","); got != want {
+		t.Errorf("Got calls %s, want %s", got, want)
+	}
+}
+
+func TestHeaderMiddleware(t *testing.T) {
+	srv := newEchoServer()
+	defer srv.Close()
+	c := NewClientWith(Config{URL: srv.URL})
+	c.Use(HeaderMiddleware(http.Header{"x-test": {"injected"}}))
+
+	p, err := c.GetPost("abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Content != "injected" {
+		t.Errorf("Header wasn't injected; got %q", p.Content)
+	}
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	srv := newEchoServer()
+	defer srv.Close()
+
+	var logs []RequestLog
+	c := NewClientWith(Config{URL: srv.URL, Token: "secret-user-token"})
+	c.SetApplicationKey("secret-api-key")
+	c.Use(LoggingMiddleware(func(l RequestLog) {
+		logs = append(logs, l)
+	}))
+
+	if err := c.DeletePost("abc", "secret-post-token"); err == nil {
+		t.Fatal("Expected error from unexpected 200 response")
+	}
+	if len(logs) != 1 {
+		t.Fatalf("Got %d logs, want 1", len(logs))
+	}
+	l := logs[0]
+	if l.Method != "DELETE" || l.Status != http.StatusOK || l.Err != nil {
+		t.Errorf("Unexpected log: %+v", l)
+	}
+	if !strings.HasSuffix(l.URL, "/posts/abc?token=REDACTED") {
+		t.Errorf("Token wasn't redacted from URL: %s", l.URL)
+	}
+	if l.Header.Get("Authorization") != "REDACTED" || l.Header.Get("X-API-Key") != "REDACTED" {
+		t.Errorf("Credentials weren't redacted from headers: %v", l.Header)
+	}
+	if s := l.URL + strings.Join(l.Header["Authorization"], "") + strings.Join(l.Header["X-Api-Key"], ""); strings.Contains(s, "secret") {
+		t.Errorf("Log contains secrets: %+v", l)
+	}
+}
+
+func TestTimingMiddleware(t *testing.T) {
+	srv := newEchoServer()
+	defer srv.Close()
+
+	var status int
+	var d time.Duration
+	c := NewClientWith(Config{URL: srv.URL})
+	c.Use(TimingMiddleware(func(r *http.Request, s int, dur time.Duration) {
+		status, d = s, dur
+	}))
+
+	if _, err := c.GetPost("abc"); err != nil {
+		t.Fatal(err)
+	}
+	if status != http.StatusOK || d <= 0 {
+		t.Errorf("Unexpected timing: status %d, duration %s", status, d)
+	}
+}
+
+func TestMiddlewareFaultInjection(t *testing.T) {
+	srv := newEchoServer()
+	defer srv.Close()
+
+	// Fail the first attempt without reaching the server
+	attempts := 0
+	c := NewClientWith(Config{URL: srv.URL, Retry: fastRetries})
+	c.Use(func(next SendFunc) SendFunc {
+		return func(r *http.Request) (*http.Response, error) {
+			attempts++
+			if attempts == 1 {
+				return &http.Response{
+					StatusCode: http.StatusServiceUnavailable,
+					Header:     http.Header{},
+					Body:       ioutil.NopCloser(bytes.NewBufferString(`{"code":503}`)),
+					Request:    r,
+				}, nil
+			}
+			return next(r)
+		}
+	})
+
+	if _, err := c.GetPost("abc"); err != nil {
+		t.Fatalf("Expected success after retry, got: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("Middleware saw %d attempts, want 2", attempts)
+	}
+}
@@ -0,0 +1,149 @@
+package writeas
+
+import (
+	"context"
+	"fmt"
+)
+
+// PinPosts pins the given posts to a collection in a single request. A post
+// with a Position of 0 is pinned after any posts that are already pinned.
+// The returned results report the outcome for each post, in the same order,
+// so a nil error doesn't mean every post was pinned; check each result's
+// Err.
+// See https://developers.write.as/docs/api/#pin-a-post-to-a-collection
+func (c *Client) PinPosts(alias string, posts []PinnedPostParams) ([]BatchPostResult, error) {
+	return c.PinPostsContext(context.Background(), alias, posts)
+}
+
+// PinPostsContext is like PinPosts, but uses ctx for the request.
+func (c *Client) PinPostsContext(ctx context.Context, alias string, posts []PinnedPostParams) ([]BatchPostResult, error) {
+	endpoint := fmt.Sprintf("/collections/%s/pin", alias)
+	return c.batchPosts(ctx, endpoint, posts, "pinning")
+}
+
+// UnpinPosts unpins the given posts from a collection in a single request.
+// Only each post's ID is used. The returned results report the outcome for
+// each post, in the same order, so a nil error doesn't mean every post was
+// unpinned; check each result's Err.
+// See https://developers.write.as/docs/api/#unpin-a-post-from-a-collection
+func (c *Client) UnpinPosts(alias string, posts []PinnedPostParams) ([]BatchPostResult, error) {
+	return c.UnpinPostsContext(context.Background(), alias, posts)
+}
+
+// UnpinPostsContext is like UnpinPosts, but uses ctx for the request.
+func (c *Client) UnpinPostsContext(ctx context.Context, alias string, posts []PinnedPostParams) ([]BatchPostResult, error) {
+	endpoint := fmt.Sprintf("/collections/%s/unpin", alias)
+	return c.batchPosts(ctx, endpoint, posts, "unpinning")
+}
+
+// PinChanges are the operations needed to change which posts are pinned to a
+// collection, and in what order. Unpin should be applied before Pin.
+type PinChanges struct {
+	Unpin []PinnedPostParams
+	Pin   []PinnedPostParams
+}
+
+// Empty returns whether no changes are needed.
+func (pc PinChanges) Empty() bool {
+	return len(pc.Unpin) == 0 && len(pc.Pin) == 0
+}
+
+// PlanPins computes the fewest pin and unpin operations needed to go from the
+// currently pinned posts, with their positions, to having exactly the posts
+// in desired pinned, in that order. Posts that are already in the right
+// relative order, with enough room between their positions for the posts
+// that belong between them, are left alone; positions don't need to be
+// consecutive. Repeated IDs in desired are ignored.
+func PlanPins(current []PinnedPostParams, desired []string) PinChanges {
+	pc := PinChanges{}
+
+	want := map[string]bool{}
+	order := []string{}
+	for _, id := range desired {
+		if !want[id] {
+			want[id] = true
+			order = append(order, id)
+		}
+	}
+	pos := map[string]int{}
+	for _, p := range current {
+		if !want[p.ID] {
+			pc.Unpin = append(pc.Unpin, PinnedPostParams{ID: p.ID})
+			continue
+		}
+		if p.Position > 0 {
+			pos[p.ID] = p.Position
+		}
+	}
+
+	// A post at index i can stay at position p only if there's room for
+	// the i posts before it, i.e. p-i >= 1, and any two posts that stay
+	// must have at least as much room between them as there are posts to
+	// fit there. Both hold when p-i never decreases across the posts that
+	// stay, so keep the longest such run.
+	n := len(order)
+	length := make([]int, n)
+	prev := make([]int, n)
+	best := -1
+	for i, id := range order {
+		prev[i] = -1
+		p, ok := pos[id]
+		if !ok || p-i < 1 {
+			continue
+		}
+		length[i] = 1
+		for j := 0; j < i; j++ {
+			if length[j] > 0 && pos[order[j]]-j <= p-i && length[j]+1 > length[i] {
+				length[i] = length[j] + 1
+				prev[i] = j
+			}
+		}
+		if best < 0 || length[i] > length[best] {
+			best = i
+		}
+	}
+	keep := make([]bool, n)
+	for i := best; i >= 0; i = prev[i] {
+		keep[i] = true
+	}
+
+	// Place every other post right after the closest kept post before it
+	lastIdx, lastPos := -1, 0
+	for i, id := range order {
+		if keep[i] {
+			lastIdx, lastPos = i, pos[id]
+			continue
+		}
+		pc.Pin = append(pc.Pin, PinnedPostParams{ID: id, Position: lastPos + i - lastIdx})
+	}
+	return pc
+}
+
+// ReorderPins changes the posts pinned to a collection from current to
+// exactly the posts in desired, in that order, using the fewest operations
+// as computed by PlanPins. It returns the results of every unpin and pin
+// operation, in that order.
+func (c *Client) ReorderPins(alias string, current []PinnedPostParams, desired []string) ([]BatchPostResult, error) {
+	return c.ReorderPinsContext(context.Background(), alias, current, desired)
+}
+
+// ReorderPinsContext is like ReorderPins, but uses ctx for the requests.
+func (c *Client) ReorderPinsContext(ctx context.Context, alias string, current []PinnedPostParams, desired []string) ([]BatchPostResult, error) {
+	pc := PlanPins(current, desired)
+	res := []BatchPostResult{}
+	if len(pc.Unpin) > 0 {
+		r, err := c.UnpinPostsContext(ctx, alias, pc.Unpin)
+		if err != nil {
+			return res, err
+		}
+		res = append(res, r...)
+	}
+	if len(pc.Pin) > 0 {
+		r, err := c.PinPostsContext(ctx, alias, pc.Pin)
+		if err != nil {
+			return res, err
+		}
+		res = append(res, r...)
+	}
+	return res, nil
+}
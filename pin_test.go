@@ -0,0 +1,178 @@
+package writeas
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPlanPins(t *testing.T) {
+	pins := func(ps ...interface{}) []PinnedPostParams {
+		var out []PinnedPostParams
+		for i := 0; i < len(ps); i += 2 {
+			out = append(out, PinnedPostParams{ID: ps[i].(string), Position: ps[i+1].(int)})
+		}
+		return out
+	}
+
+	tests := []struct {
+		name    string
+		current []PinnedPostParams
+		desired []string
+		want    PinChanges
+	}{
+		{
+			name:    "no changes",
+			current: pins("a", 1, "b", 2),
+			desired: []string{"a", "b"},
+			want:    PinChanges{},
+		},
+		{
+			name:    "pin from nothing",
+			desired: []string{"a", "b"},
+			want:    PinChanges{Pin: pins("a", 1, "b", 2)},
+		},
+		{
+			name:    "unpin all",
+			current: pins("a", 1, "b", 2),
+			want:    PinChanges{Unpin: pins("a", 0, "b", 0)},
+		},
+		{
+			name:    "remove first keeps gaps",
+			current: pins("a", 1, "b", 2, "c", 3),
+			desired: []string{"b", "c"},
+			want:    PinChanges{Unpin: pins("a", 0)},
+		},
+		{
+			name:    "move last to front",
+			current: pins("a", 1, "b", 2, "c", 3),
+			desired: []string{"c", "a", "b"},
+			want:    PinChanges{Pin: pins("a", 4, "b", 5)},
+		},
+		{
+			name:    "move first to end",
+			current: pins("a", 1, "b", 2, "c", 3),
+			desired: []string{"b", "c", "a"},
+			want:    PinChanges{Pin: pins("a", 4)},
+		},
+		{
+			name:    "insert into gap",
+			current: pins("a", 1, "b", 3),
+			desired: []string{"a", "x", "b"},
+			want:    PinChanges{Pin: pins("x", 2)},
+		},
+		{
+			name:    "insert without room",
+			current: pins("a", 1, "b", 2),
+			desired: []string{"a", "x", "b"},
+			want:    PinChanges{Pin: pins("x", 2, "b", 3)},
+		},
+		{
+			name:    "repeated ids",
+			current: pins("a", 1),
+			desired: []string{"a", "b", "a"},
+			want:    PinChanges{Pin: pins("b", 2)},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := PlanPins(test.current, test.desired)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPinPosts(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	a, _ := srv.AddCollectionPost("tester", "A", "Post A.")
+	b, _ := srv.AddCollectionPost("tester", "B", "Post B.")
+	d, _ := srv.AddCollectionPost("tester", "C", "Post C.")
+	c := NewClientWith(Config{URL: srv.APIURL()})
+	if _, err := c.LogIn("demo", "demo"); err != nil {
+		t.Fatalf("Unable to log in: %v", err)
+	}
+
+	pinned := func(n int) []string {
+		t.Helper()
+		posts, err := c.GetCollectionPosts("tester")
+		if err != nil {
+			t.Fatalf("Unable to get posts: %v", err)
+		}
+		var ids []string
+		for _, p := range (*posts)[:n] {
+			ids = append(ids, p.ID)
+		}
+		return ids
+	}
+
+	res, err := c.PinPosts("tester", []PinnedPostParams{
+		{ID: a, Position: 1},
+		{ID: b, Position: 2},
+		{ID: "doesnotexist", Position: 3},
+	})
+	if err != nil {
+		t.Fatalf("Pin failed: %v", err)
+	}
+	if len(res) != 3 {
+		t.Fatalf("Got %d results, want 3", len(res))
+	}
+	for _, r := range res[:2] {
+		if err := r.Err(); err != nil {
+			t.Errorf("Unable to pin %s: %v", r.ID, err)
+		}
+	}
+	if err := res[2].Err(); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got: %v", err)
+	} else if res[2].ErrorMessage == "" {
+		t.Errorf("Expected an error message for missing post")
+	}
+	if got, want := pinned(2), []string{a, b}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Pinned %v, want %v", got, want)
+	}
+
+	t.Run("reorder", func(t *testing.T) {
+		current := []PinnedPostParams{{ID: a, Position: 1}, {ID: b, Position: 2}}
+		res, err := c.ReorderPins("tester", current, []string{d, b})
+		if err != nil {
+			t.Fatalf("Reorder failed: %v", err)
+		}
+		for _, r := range res {
+			if err := r.Err(); err != nil {
+				t.Errorf("Unable to reorder %s: %v", r.ID, err)
+			}
+		}
+		if got, want := pinned(2), []string{d, b}; !reflect.DeepEqual(got, want) {
+			t.Errorf("Pinned %v, want %v", got, want)
+		}
+	})
+
+	t.Run("unpin", func(t *testing.T) {
+		res, err := c.UnpinPosts("tester", []PinnedPostParams{{ID: b}, {ID: d}})
+		if err != nil {
+			t.Fatalf("Unpin failed: %v", err)
+		}
+		for _, r := range res {
+			if err := r.Err(); err != nil {
+				t.Errorf("Unable to unpin %s: %v", r.ID, err)
+			}
+		}
+	})
+
+	t.Run("single post error", func(t *testing.T) {
+		err := c.PinPost("tester", &PinnedPostParams{ID: "doesnotexist"})
+		if !errors.Is(err, ErrNotFound) {
+			t.Fatalf("Expected ErrNotFound, got: %v", err)
+		}
+		// The error reads the same as when pinning in a batch
+		res, err2 := c.PinPosts("tester", []PinnedPostParams{{ID: "doesnotexist"}})
+		if err2 != nil {
+			t.Fatal(err2)
+		}
+		if want := res[0].Err().Error(); err.Error() != want {
+			t.Errorf("Got error %q, want %q", err, want)
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package writeas
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"time"
@@ -60,6 +61,10 @@ type (
 
 		// Parameters for collection posts
 		Collection string `json:"-"`
+
+		// SkipLanguageDetection stops the Client's LanguageDetector, if
+		// any, from filling in Language and IsRTL.
+		SkipLanguageDetection bool `json:"-"`
 	}
 
 	// PinnedPostParams holds values for pinning a post
@@ -74,6 +79,9 @@ type (
 		ID           string `json:"id,omitempty"`
 		Code         int    `json:"code,omitempty"`
 		ErrorMessage string `json:"error_msg,omitempty"`
+
+		// Post is the affected post, for operations that return it.
+		Post *Post `json:"post,omitempty"`
 	}
 
 	// ClaimPostResult contains the post-specific result for a request to
@@ -86,6 +94,33 @@ type (
 	}
 )
 
+// Err returns an *APIError describing why the operation failed on this post,
+// or nil if it succeeded.
+func (r *BatchPostResult) Err() error {
+	return postResultErr(r.ID, r.Code, r.ErrorMessage)
+}
+
+// Err returns an *APIError describing why claiming this post failed, or nil
+// if it succeeded.
+func (r *ClaimPostResult) Err() error {
+	return postResultErr(r.ID, r.Code, r.ErrorMessage)
+}
+
+func postResultErr(id string, code int, msg string) error {
+	if code >= 200 && code < 300 {
+		return nil
+	}
+	desc := fmt.Sprintf("Problem with post %s: %d", id, code)
+	if msg != "" {
+		desc += ". " + msg
+	}
+	return &APIError{
+		StatusCode: code,
+		Message:    msg,
+		desc:       desc,
+	}
+}
+
 type PostType string
 
 const (
@@ -100,8 +135,14 @@ const (
 // user-friendly form) that occurs. See
 // https://developers.write.as/docs/api/#retrieve-a-post.
 func (c *Client) GetPost(id string) (*Post, error) {
+	return c.GetPostContext(context.Background(), id)
+}
+
+// GetPostContext is like GetPost, but uses ctx for the request.
+func (c *Client) GetPostContext(ctx context.Context, id string) (*Post, error) {
 	p := &Post{}
-	env, err := c.get(fmt.Sprintf("/posts/%s", id), p)
+	endpoint := "/posts/" + id
+	env, err := c.get(ctx, endpoint, p)
 	if err != nil {
 		return nil, err
 	}
@@ -115,22 +156,30 @@ func (c *Client) GetPost(id string) (*Post, error) {
 	if status == http.StatusOK {
 		return p, nil
 	} else if status == http.StatusNotFound {
-		return nil, fmt.Errorf("Post not found.")
+		return nil, newAPIError("GET", endpoint, env, "Post not found.")
 	} else if status == http.StatusGone {
-		return nil, fmt.Errorf("Post unpublished.")
+		return nil, newAPIError("GET", endpoint, env, "Post unpublished.")
 	}
-	return nil, fmt.Errorf("Problem getting post: %d. %s\n", status, env.ErrorMessage)
+	return nil, newAPIError("GET", endpoint, env, fmt.Sprintf("Problem getting post: %d. %s", status, env.ErrorMessage))
 }
 
 // CreatePost publishes a new post, returning a user-friendly error if one comes
-// up. See https://developers.write.as/docs/api/#publish-a-post.
+// up. If the Client has a TokenStore, the token of an anonymous post is
+// recorded there; if that fails, both the post and an error are returned.
+// See https://developers.write.as/docs/api/#publish-a-post.
 func (c *Client) CreatePost(sp *PostParams) (*Post, error) {
+	return c.CreatePostContext(context.Background(), sp)
+}
+
+// CreatePostContext is like CreatePost, but uses ctx for the request.
+func (c *Client) CreatePostContext(ctx context.Context, sp *PostParams) (*Post, error) {
 	p := &Post{}
 	endPre := ""
 	if sp.Collection != "" {
 		endPre = "/collections/" + sp.Collection
 	}
-	env, err := c.post(endPre+"/posts", sp, p)
+	endpoint := endPre + "/posts"
+	env, err := c.post(ctx, endpoint, c.withDetectedLanguage(sp), p)
 	if err != nil {
 		return nil, err
 	}
@@ -143,31 +192,57 @@ func (c *Client) CreatePost(sp *PostParams) (*Post, error) {
 	status := env.Code
 	if status != http.StatusCreated {
 		if status == http.StatusBadRequest {
-			return nil, fmt.Errorf("Bad request: %s", env.ErrorMessage)
+			return nil, newAPIError("POST", endpoint, env, fmt.Sprintf("Bad request: %s", env.ErrorMessage))
+		}
+		return nil, newAPIError("POST", endpoint, env, fmt.Sprintf("Problem creating post: %d. %s", status, env.ErrorMessage))
+	}
+	if s := c.tokenStore(); s != nil && p.Token != "" {
+		if err := s.Put(p.ID, p.Token); err != nil {
+			// Still return the post, since its token can't be retrieved again
+			return p, fmt.Errorf("post created, but unable to store its token: %v", err)
 		}
-		return nil, fmt.Errorf("Problem creating post: %d. %s\n", status, env.ErrorMessage)
 	}
 	return p, nil
 }
 
-// UpdatePost updates a published post with the given PostParams. See
-// https://developers.write.as/docs/api/#update-a-post.
+// UpdatePost updates a published post with the given PostParams. If token is
+// empty and the Client has a TokenStore, the post's token is looked up
+// there. See https://developers.write.as/docs/api/#update-a-post.
 func (c *Client) UpdatePost(id, token string, sp *PostParams) (*Post, error) {
-	return c.updatePost("", id, token, sp)
+	return c.UpdatePostContext(context.Background(), id, token, sp)
+}
+
+// UpdatePostContext is like UpdatePost, but uses ctx for the request.
+func (c *Client) UpdatePostContext(ctx context.Context, id, token string, sp *PostParams) (*Post, error) {
+	return c.updatePost(ctx, "", id, token, sp)
 }
 
-func (c *Client) updatePost(collection, identifier, token string, sp *PostParams) (*Post, error) {
+// UpdateCollectionPost updates the post with the given slug on a collection,
+// authenticating with the user's access token instead of a post token.
+func (c *Client) UpdateCollectionPost(alias, slug string, sp *PostParams) (*Post, error) {
+	return c.UpdateCollectionPostContext(context.Background(), alias, slug, sp)
+}
+
+// UpdateCollectionPostContext is like UpdateCollectionPost, but uses ctx for the request.
+func (c *Client) UpdateCollectionPostContext(ctx context.Context, alias, slug string, sp *PostParams) (*Post, error) {
+	return c.updatePost(ctx, alias, slug, "", sp)
+}
+
+func (c *Client) updatePost(ctx context.Context, collection, identifier, token string, sp *PostParams) (*Post, error) {
 	p := &Post{}
 	endpoint := "/posts/" + identifier
-	/*
-		if collection != "" {
-			endpoint = "/collections/" + collection + endpoint
-		} else {
-			sp.Token = token
+	if collection != "" {
+		endpoint = "/collections/" + collection + endpoint
+	} else {
+		if token == "" {
+			var err error
+			if token, err = c.storedToken(identifier); err != nil {
+				return nil, err
+			}
 		}
-	*/
-	sp.Token = token
-	env, err := c.put(endpoint, sp, p)
+		sp.Token = token
+	}
+	env, err := c.put(ctx, endpoint, c.withDetectedLanguage(sp), p)
 	if err != nil {
 		return nil, err
 	}
@@ -180,53 +255,99 @@ func (c *Client) updatePost(collection, identifier, token string, sp *PostParams
 	status := env.Code
 	if status != http.StatusOK {
 		if c.isNotLoggedIn(status) {
-			return nil, fmt.Errorf("Not authenticated.")
+			return nil, newAPIError("PUT", endpoint, env, "Not authenticated.")
 		} else if status == http.StatusBadRequest {
-			return nil, fmt.Errorf("Bad request: %s", env.ErrorMessage)
+			return nil, newAPIError("PUT", endpoint, env, fmt.Sprintf("Bad request: %s", env.ErrorMessage))
 		}
-		return nil, fmt.Errorf("Problem updating post: %d. %s\n", status, env.ErrorMessage)
+		return nil, newAPIError("PUT", endpoint, env, fmt.Sprintf("Problem updating post: %d. %s", status, env.ErrorMessage))
 	}
 	return p, nil
 }
 
-// DeletePost permanently deletes a published post. See
+// DeletePost permanently deletes a published post. If token is empty and the
+// Client has a TokenStore, the post's token is looked up there, and removed
+// once the post is deleted. See
 // https://developers.write.as/docs/api/#delete-a-post.
 func (c *Client) DeletePost(id, token string) error {
-	return c.deletePost("", id, token)
+	return c.DeletePostContext(context.Background(), id, token)
 }
 
-func (c *Client) deletePost(collection, identifier, token string) error {
+// DeletePostContext is like DeletePost, but uses ctx for the request.
+func (c *Client) DeletePostContext(ctx context.Context, id, token string) error {
+	return c.deletePost(ctx, "", id, token)
+}
+
+// DeleteCollectionPost permanently deletes the post with the given slug on a
+// collection, authenticating with the user's access token instead of a post
+// token.
+func (c *Client) DeleteCollectionPost(alias, slug string) error {
+	return c.DeleteCollectionPostContext(context.Background(), alias, slug)
+}
+
+// DeleteCollectionPostContext is like DeleteCollectionPost, but uses ctx for the request.
+func (c *Client) DeleteCollectionPostContext(ctx context.Context, alias, slug string) error {
+	return c.deletePost(ctx, alias, slug, "")
+}
+
+func (c *Client) deletePost(ctx context.Context, collection, identifier, token string) error {
 	p := map[string]string{}
 	endpoint := "/posts/" + identifier
-	/*
-		if collection != "" {
-			endpoint = "/collections/" + collection + endpoint
-		} else {
-			p["token"] = token
+	if collection != "" {
+		endpoint = "/collections/" + collection + endpoint
+	} else {
+		if token == "" {
+			var err error
+			if token, err = c.storedToken(identifier); err != nil {
+				return err
+			}
 		}
-	*/
-	p["token"] = token
-	env, err := c.delete(endpoint, p)
+		p["token"] = token
+	}
+	env, err := c.delete(ctx, endpoint, p)
 	if err != nil {
 		return err
 	}
 
 	status := env.Code
 	if status == http.StatusNoContent {
+		if s := c.tokenStore(); s != nil && collection == "" {
+			// The post is gone either way, so a stale token is harmless
+			s.Delete(identifier)
+		}
 		return nil
 	} else if c.isNotLoggedIn(status) {
-		return fmt.Errorf("Not authenticated.")
+		return newAPIError("DELETE", endpoint, env, "Not authenticated.")
 	} else if status == http.StatusBadRequest {
-		return fmt.Errorf("Bad request: %s", env.ErrorMessage)
+		return newAPIError("DELETE", endpoint, env, fmt.Sprintf("Bad request: %s", env.ErrorMessage))
 	}
-	return fmt.Errorf("Problem deleting post: %d. %s\n", status, env.ErrorMessage)
+	return newAPIError("DELETE", endpoint, env, fmt.Sprintf("Problem deleting post: %d. %s", status, env.ErrorMessage))
+}
+
+// storedToken returns the token for the given post from the Client's
+// TokenStore, if it has one.
+func (c *Client) storedToken(id string) (string, error) {
+	s := c.tokenStore()
+	if s == nil {
+		return "", nil
+	}
+	token, err := s.Get(id)
+	if err != nil {
+		return "", fmt.Errorf("Unable to look up post token: %v", err)
+	}
+	return token, nil
 }
 
 // ClaimPosts associates anonymous posts with a user / account.
 // https://developers.write.as/docs/api/#claim-posts.
 func (c *Client) ClaimPosts(sp *[]OwnedPostParams) (*[]ClaimPostResult, error) {
+	return c.ClaimPostsContext(context.Background(), sp)
+}
+
+// ClaimPostsContext is like ClaimPosts, but uses ctx for the request.
+func (c *Client) ClaimPostsContext(ctx context.Context, sp *[]OwnedPostParams) (*[]ClaimPostResult, error) {
 	p := &[]ClaimPostResult{}
-	env, err := c.post("/posts/claim", sp, p)
+	endpoint := "/posts/claim"
+	env, err := c.post(ctx, endpoint, sp, p)
 	if err != nil {
 		return nil, err
 	}
@@ -240,20 +361,82 @@ func (c *Client) ClaimPosts(sp *[]OwnedPostParams) (*[]ClaimPostResult, error) {
 	if status == http.StatusOK {
 		return p, nil
 	} else if c.isNotLoggedIn(status) {
-		return nil, fmt.Errorf("Not authenticated.")
+		return nil, newAPIError("POST", endpoint, env, "Not authenticated.")
 	} else if status == http.StatusBadRequest {
-		return nil, fmt.Errorf("Bad request: %s", env.ErrorMessage)
+		return nil, newAPIError("POST", endpoint, env, fmt.Sprintf("Bad request: %s", env.ErrorMessage))
 	} else {
-		return nil, fmt.Errorf("Problem claiming post: %d. %s\n", status, env.ErrorMessage)
+		return nil, newAPIError("POST", endpoint, env, fmt.Sprintf("Problem claiming post: %d. %s", status, env.ErrorMessage))
 	}
 	// TODO: does this also happen with moving posts?
 }
 
+// MovePosts moves the given posts into a collection owned by the
+// authenticated user. Anonymous posts must include their tokens. The
+// returned results report the outcome for each post, in the same order, so
+// a nil error doesn't mean every post was moved; check each result's Err.
+// See https://developers.write.as/docs/api/#move-a-post-to-a-collection
+func (c *Client) MovePosts(alias string, posts []OwnedPostParams) ([]BatchPostResult, error) {
+	return c.MovePostsContext(context.Background(), alias, posts)
+}
+
+// MovePostsContext is like MovePosts, but uses ctx for the request.
+func (c *Client) MovePostsContext(ctx context.Context, alias string, posts []OwnedPostParams) ([]BatchPostResult, error) {
+	endpoint := "/collections/" + alias + "/collect"
+	return c.batchPosts(ctx, endpoint, posts, "moving")
+}
+
+// UnpublishPosts removes the given posts from whatever collection they're on,
+// turning them back into drafts owned by the authenticated user. The
+// returned results report the outcome for each post, in the same order, so
+// a nil error doesn't mean every post was unpublished; check each result's
+// Err.
+func (c *Client) UnpublishPosts(ids []string) ([]BatchPostResult, error) {
+	return c.UnpublishPostsContext(context.Background(), ids)
+}
+
+// UnpublishPostsContext is like UnpublishPosts, but uses ctx for the request.
+func (c *Client) UnpublishPostsContext(ctx context.Context, ids []string) ([]BatchPostResult, error) {
+	return c.batchPosts(ctx, "/posts/disperse", ids, "unpublishing")
+}
+
+// batchPosts POSTs the given batch operation to endpoint, returning the
+// per-post results. An error is only returned if the request as a whole
+// fails.
+func (c *Client) batchPosts(ctx context.Context, endpoint string, data interface{}, action string) ([]BatchPostResult, error) {
+	res := &[]BatchPostResult{}
+	env, err := c.post(ctx, endpoint, data, res)
+	if err != nil {
+		return nil, err
+	}
+
+	var ok bool
+	if res, ok = env.Data.(*[]BatchPostResult); !ok {
+		return nil, fmt.Errorf("Wrong data returned from API.")
+	}
+
+	status := env.Code
+	if status != http.StatusOK {
+		if c.isNotLoggedIn(status) {
+			return nil, newAPIError("POST", endpoint, env, "Not authenticated.")
+		} else if status == http.StatusBadRequest {
+			return nil, newAPIError("POST", endpoint, env, fmt.Sprintf("Bad request: %s", env.ErrorMessage))
+		}
+		return nil, newAPIError("POST", endpoint, env, fmt.Sprintf("Problem %s posts: %d. %s", action, status, env.ErrorMessage))
+	}
+	return *res, nil
+}
+
 // GetUserPosts retrieves the authenticated user's posts.
 // See https://developers.write.as/docs/api/#retrieve-user-39-s-posts
 func (c *Client) GetUserPosts() (*[]Post, error) {
+	return c.GetUserPostsContext(context.Background())
+}
+
+// GetUserPostsContext is like GetUserPosts, but uses ctx for the request.
+func (c *Client) GetUserPostsContext(ctx context.Context) (*[]Post, error) {
 	p := &[]Post{}
-	env, err := c.get("/me/posts", p)
+	endpoint := "/me/posts"
+	env, err := c.get(ctx, endpoint, p)
 	if err != nil {
 		return nil, err
 	}
@@ -266,9 +449,9 @@ func (c *Client) GetUserPosts() (*[]Post, error) {
 
 	if status != http.StatusOK {
 		if c.isNotLoggedIn(status) {
-			return nil, fmt.Errorf("Not authenticated.")
+			return nil, newAPIError("GET", endpoint, env, "Not authenticated.")
 		}
-		return nil, fmt.Errorf("Problem getting user posts: %d. %s\n", status, env.ErrorMessage)
+		return nil, newAPIError("GET", endpoint, env, fmt.Sprintf("Problem getting user posts: %d. %s", status, env.ErrorMessage))
 	}
 	return p, nil
 }
@@ -276,69 +459,35 @@ func (c *Client) GetUserPosts() (*[]Post, error) {
 // PinPost pins a post in the given collection.
 // See https://developers.write.as/docs/api/#pin-a-post-to-a-collection
 func (c *Client) PinPost(alias string, pp *PinnedPostParams) error {
-	res := &[]BatchPostResult{}
-	env, err := c.post(fmt.Sprintf("/collections/%s/pin", alias), []*PinnedPostParams{pp}, res)
+	return c.PinPostContext(context.Background(), alias, pp)
+}
+
+// PinPostContext is like PinPost, but uses ctx for the request.
+func (c *Client) PinPostContext(ctx context.Context, alias string, pp *PinnedPostParams) error {
+	res, err := c.PinPostsContext(ctx, alias, []PinnedPostParams{*pp})
 	if err != nil {
 		return err
 	}
-
-	var ok bool
-	if res, ok = env.Data.(*[]BatchPostResult); !ok {
+	if len(res) != 1 {
 		return fmt.Errorf("Wrong data returned from API.")
 	}
-
-	// Check for basic request errors on top level response
-	status := env.Code
-	if status != http.StatusOK {
-		if c.isNotLoggedIn(status) {
-			return fmt.Errorf("Not authenticated.")
-		}
-		return fmt.Errorf("Problem pinning post: %d. %s\n", status, env.ErrorMessage)
-	}
-
-	// Check the individual post result
-	if len(*res) == 0 || len(*res) > 1 {
-		return fmt.Errorf("Wrong data returned from API.")
-	}
-	if (*res)[0].Code != http.StatusOK {
-		return fmt.Errorf("Problem pinning post: %d", (*res)[0].Code)
-		// TODO: return ErrorMessage (right now it'll be empty)
-		// return fmt.Errorf("Problem pinning post: %s", res[0].ErrorMessage)
-	}
-	return nil
+	return res[0].Err()
 }
 
 // UnpinPost unpins a post from the given collection.
 // See https://developers.write.as/docs/api/#unpin-a-post-from-a-collection
 func (c *Client) UnpinPost(alias string, pp *PinnedPostParams) error {
-	res := &[]BatchPostResult{}
-	env, err := c.post(fmt.Sprintf("/collections/%s/unpin", alias), []*PinnedPostParams{pp}, res)
+	return c.UnpinPostContext(context.Background(), alias, pp)
+}
+
+// UnpinPostContext is like UnpinPost, but uses ctx for the request.
+func (c *Client) UnpinPostContext(ctx context.Context, alias string, pp *PinnedPostParams) error {
+	res, err := c.UnpinPostsContext(ctx, alias, []PinnedPostParams{*pp})
 	if err != nil {
 		return err
 	}
-
-	var ok bool
-	if res, ok = env.Data.(*[]BatchPostResult); !ok {
-		return fmt.Errorf("Wrong data returned from API.")
-	}
-
-	// Check for basic request errors on top level response
-	status := env.Code
-	if status != http.StatusOK {
-		if c.isNotLoggedIn(status) {
-			return fmt.Errorf("Not authenticated.")
-		}
-		return fmt.Errorf("Problem unpinning post: %d. %s\n", status, env.ErrorMessage)
-	}
-
-	// Check the individual post result
-	if len(*res) == 0 || len(*res) > 1 {
+	if len(res) != 1 {
 		return fmt.Errorf("Wrong data returned from API.")
 	}
-	if (*res)[0].Code != http.StatusOK {
-		return fmt.Errorf("Problem unpinning post: %d", (*res)[0].Code)
-		// TODO: return ErrorMessage (right now it'll be empty)
-		// return fmt.Errorf("Problem unpinning post: %s", res[0].ErrorMessage)
-	}
-	return nil
+	return res[0].Err()
 }
@@ -1,13 +1,19 @@
 package writeas
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
 	"testing"
+
+	"github.com/writeas/go-writeas/v2/writeastest"
 )
 
 func TestPostRoundTrip(t *testing.T) {
 	var id, token string
-	dwac := NewClient()
+	srv := newTestServer()
+	defer srv.Close()
+	dwac := NewClientWith(Config{URL: srv.APIURL()})
 	t.Run("Create post", func(t *testing.T) {
 		p, err := dwac.CreatePost(&PostParams{
 			Title:   "Title!",
@@ -53,7 +59,10 @@ func TestPostRoundTrip(t *testing.T) {
 }
 
 func TestPinUnPin(t *testing.T) {
-	dwac := NewDevClient()
+	srv := newTestServer()
+	defer srv.Close()
+	postID, _ := srv.AddCollectionPost("tester", "Pin me", "This post will be pinned.")
+	dwac := NewClientWith(Config{URL: srv.APIURL()})
 	_, err := dwac.LogIn("demo", "demo")
 	if err != nil {
 		t.Fatalf("Unable to log in: %v", err)
@@ -61,13 +70,13 @@ func TestPinUnPin(t *testing.T) {
 	defer dwac.LogOut()
 
 	t.Run("Pin post", func(t *testing.T) {
-		err := dwac.PinPost("tester", &PinnedPostParams{ID: "olx6uk7064heqltf"})
+		err := dwac.PinPost("tester", &PinnedPostParams{ID: postID})
 		if err != nil {
 			t.Fatalf("Pin failed: %v", err)
 		}
 	})
 	t.Run("Unpin post", func(t *testing.T) {
-		err := dwac.UnpinPost("tester", &PinnedPostParams{ID: "olx6uk7064heqltf"})
+		err := dwac.UnpinPost("tester", &PinnedPostParams{ID: postID})
 		if err != nil {
 			t.Fatalf("Unpin failed: %v", err)
 		}
@@ -75,7 +84,10 @@ func TestPinUnPin(t *testing.T) {
 }
 
 func ExampleClient_CreatePost() {
-	dwac := NewDevClient()
+	// Use a fake Write.as server for this example
+	srv := writeastest.NewServer()
+	defer srv.Close()
+	dwac := NewClientWith(Config{URL: srv.APIURL()})
 
 	// Publish a post
 	p, err := dwac.CreatePost(&PostParams{
@@ -91,3 +103,113 @@ func ExampleClient_CreatePost() {
 	fmt.Printf("%s", p.Content)
 	// Output: This is a post.
 }
+
+func TestMoveAndUnpublishPosts(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	anonID, anonToken := srv.AddPost("Anonymous", "An anonymous post.")
+	otherID, _ := srv.AddPost("Someone else's", "Not yours.")
+	dwac := NewClientWith(Config{URL: srv.APIURL()})
+	if _, err := dwac.LogIn("demo", "demo"); err != nil {
+		t.Fatalf("Unable to log in: %v", err)
+	}
+
+	res, err := dwac.MovePosts("tester", []OwnedPostParams{
+		{ID: anonID, Token: anonToken},
+		{ID: otherID, Token: "wrong-token"},
+		{ID: "doesnotexist"},
+	})
+	if err != nil {
+		t.Fatalf("Move failed: %v", err)
+	}
+	if len(res) != 3 {
+		t.Fatalf("Got %d results, want 3", len(res))
+	}
+	if err := res[0].Err(); err != nil {
+		t.Errorf("Unable to move post: %v", err)
+	}
+	if res[0].Post == nil || res[0].Post.Collection == nil || res[0].Post.Collection.Alias != "tester" {
+		t.Errorf("Moved post isn't on collection: %+v", res[0].Post)
+	}
+	if res[1].Err() == nil || res[1].Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for wrong token, got: %+v", res[1])
+	}
+	if !errors.Is(res[2].Err(), ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for missing post, got: %v", res[2].Err())
+	}
+
+	p, err := dwac.GetCollectionPost("tester", res[0].Post.Slug)
+	if err != nil {
+		t.Fatalf("Moved post not found on collection: %v", err)
+	}
+
+	res, err = dwac.UnpublishPosts([]string{p.ID, otherID})
+	if err != nil {
+		t.Fatalf("Unpublish failed: %v", err)
+	}
+	if err := res[0].Err(); err != nil {
+		t.Errorf("Unable to unpublish post: %v", err)
+	}
+	if res[1].Err() == nil {
+		t.Errorf("Expected error unpublishing someone else's post")
+	}
+	if _, err := dwac.GetCollectionPost("tester", p.Slug); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Unpublished post should no longer be on collection, got: %v", err)
+	}
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		_, err := dwac.WithToken("").MovePosts("tester", []OwnedPostParams{{ID: anonID}})
+		if !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("Expected ErrUnauthorized, got: %v", err)
+		}
+	})
+}
+
+func TestCollectionPostUpdateDelete(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	_, slug := srv.AddCollectionPost("tester", "Edit me", "Original content.")
+	dwac := NewClientWith(Config{URL: srv.APIURL()})
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		_, err := dwac.UpdateCollectionPost("tester", slug, &PostParams{Content: "Nope."})
+		if !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("Expected ErrUnauthorized, got: %v", err)
+		}
+	})
+
+	if _, err := dwac.LogIn("demo", "demo"); err != nil {
+		t.Fatalf("Unable to log in: %v", err)
+	}
+
+	t.Run("Update post", func(t *testing.T) {
+		p, err := dwac.UpdateCollectionPost("tester", slug, &PostParams{Content: "Updated content."})
+		if err != nil {
+			t.Fatalf("Post update failed: %v", err)
+		}
+		if p.Content != "Updated content." {
+			t.Errorf("Post wasn't updated: %+v", p)
+		}
+		p, err = dwac.GetCollectionPost("tester", slug)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Content != "Updated content." {
+			t.Errorf("Post wasn't updated: %+v", p)
+		}
+	})
+	t.Run("Update missing post", func(t *testing.T) {
+		_, err := dwac.UpdateCollectionPost("tester", "does-not-exist", &PostParams{Content: "Hi"})
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got: %v", err)
+		}
+	})
+	t.Run("Delete post", func(t *testing.T) {
+		if err := dwac.DeleteCollectionPost("tester", slug); err != nil {
+			t.Fatalf("Post delete failed: %v", err)
+		}
+		if _, err := dwac.GetCollectionPost("tester", slug); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected deleted post to be gone, got: %v", err)
+		}
+	})
+}
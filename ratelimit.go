@@ -0,0 +1,112 @@
+package writeas
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter limits how often requests are made, using a token bucket that
+// refills at a constant rate. A single RateLimiter is safe to share between
+// goroutines and between multiple Clients talking to the same host, so that
+// they all stay under the same limit together.
+type RateLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	tokens  float64
+	last    time.Time
+	waiting int
+}
+
+// NewRateLimiter creates a RateLimiter that allows perSecond requests per
+// second on average, with bursts of up to burst requests at once. If
+// perSecond isn't positive, requests are never limited.
+//
+//	// Allow 2 requests per second, or 5 at once
+//	l := writeas.NewRateLimiter(2, 5)
+//	c := writeas.NewClientWith(writeas.Config{RateLimiter: l})
+func NewRateLimiter(perSecond float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:   perSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// refill adds any tokens that have accumulated since the last call. The
+// caller must hold l.mu.
+func (l *RateLimiter) refill(now time.Time) {
+	if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens += elapsed.Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+}
+
+// Wait blocks until a request is allowed to be made, or ctx is done, in which
+// case it returns the context's error.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l.rate <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	l.refill(time.Now())
+	l.tokens--
+	if l.tokens >= 0 {
+		l.mu.Unlock()
+		return nil
+	}
+	// Reserve the next token, which will be available once the deficit
+	// refills
+	delay := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.waiting++
+	l.mu.Unlock()
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		l.mu.Lock()
+		l.waiting--
+		l.mu.Unlock()
+		return nil
+	case <-ctx.Done():
+		// Give back the reserved token
+		l.mu.Lock()
+		l.waiting--
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// Delay returns how long a request made right now would have to wait before
+// being sent. Schedulers can use it to back off before queueing more work.
+func (l *RateLimiter) Delay() time.Duration {
+	if l.rate <= 0 {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill(time.Now())
+	if l.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
+
+// Waiting returns the number of requests currently blocked waiting for the
+// RateLimiter.
+func (l *RateLimiter) Waiting() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.waiting
+}
@@ -0,0 +1,122 @@
+package writeas
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterBurst(t *testing.T) {
+	l := NewRateLimiter(1, 3)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := l.Wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d > 100*time.Millisecond {
+		t.Errorf("Burst took %s, expected no waiting", d)
+	}
+	if d := l.Delay(); d < 900*time.Millisecond || d > time.Second {
+		t.Errorf("Delay() = %s after exhausting burst, want ~1s", d)
+	}
+}
+
+func TestRateLimiterUnlimited(t *testing.T) {
+	for _, rate := range []float64{0, -1} {
+		l := NewRateLimiter(rate, 1)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		for i := 0; i < 10; i++ {
+			if err := l.Wait(ctx); err != nil {
+				t.Fatalf("Wait with rate %v: %v", rate, err)
+			}
+		}
+		cancel()
+		if d := l.Delay(); d != 0 {
+			t.Errorf("Delay() = %s with rate %v, want 0", d, rate)
+		}
+	}
+}
+
+func TestRateLimiterWaitCanceled(t *testing.T) {
+	l := NewRateLimiter(0.1, 1)
+	l.Wait(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- l.Wait(ctx)
+	}()
+
+	// Wait for the goroutine to block on the limiter
+	for l.Waiting() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("Expected context.Canceled, got: %v", err)
+	}
+	if n := l.Waiting(); n != 0 {
+		t.Errorf("Waiting() = %d after cancel, want 0", n)
+	}
+}
+
+func TestRateLimiterSharedClients(t *testing.T) {
+	var reqs int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&reqs, 1)
+		w.Write([]byte(`{"code":200,"data":{"id":"abc"}}`))
+	}))
+	defer srv.Close()
+
+	// Two clients sharing 20 requests per second, with a burst of 5
+	l := NewRateLimiter(20, 5)
+	clients := []*Client{
+		NewClientWith(Config{URL: srv.URL, RateLimiter: l}),
+		NewClientWith(Config{URL: srv.URL, RateLimiter: l}),
+	}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(c *Client) {
+			defer wg.Done()
+			if _, err := c.GetPost("abc"); err != nil {
+				t.Error(err)
+			}
+		}(clients[i%2])
+	}
+	wg.Wait()
+
+	// The 5 requests beyond the burst need 250ms to refill
+	if d := time.Since(start); d < 200*time.Millisecond {
+		t.Errorf("10 requests took %s, expected rate limiting", d)
+	}
+	if n := atomic.LoadInt32(&reqs); n != 10 {
+		t.Errorf("Server got %d requests, want 10", n)
+	}
+}
+
+func TestRateLimiterContextDeadline(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Request shouldn't have been sent")
+	}))
+	defer srv.Close()
+
+	l := NewRateLimiter(0.1, 1)
+	l.Wait(context.Background())
+	c := NewClientWith(Config{URL: srv.URL, RateLimiter: l})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := c.GetPostContext(ctx, "abc"); err != context.DeadlineExceeded {
+		t.Fatalf("Expected context.DeadlineExceeded, got: %v", err)
+	}
+}
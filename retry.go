@@ -0,0 +1,188 @@
+package writeas
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultMinBackoff = 500 * time.Millisecond
+	defaultMaxBackoff = 30 * time.Second
+)
+
+// RetryPolicy configures how a Client retries requests that fail because of
+// transient errors, like connection resets, 5xx responses, or rate limiting.
+//
+// Idempotent requests (GET, PUT, DELETE) are retried after any transient
+// error. POST requests are only retried when the server definitely didn't
+// act on them -- when the connection couldn't be established, or the
+// server responded with 429 Too Many Requests -- unless RetryNonIdempotent is
+// set.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of times a request is attempted,
+	// including the first one. Values less than 2 disable retries.
+	MaxAttempts int
+
+	// MinBackoff is the delay before the first retry, which doubles with each
+	// subsequent attempt. Defaults to 500ms.
+	MinBackoff time.Duration
+
+	// MaxBackoff caps the delay between attempts. If the server asks us to
+	// wait longer than this with a Retry-After header, the request isn't
+	// retried. Defaults to 30s.
+	MaxBackoff time.Duration
+
+	// RetryNonIdempotent allows POST requests to be retried after connection
+	// errors and 5xx responses, even though the server might have already
+	// handled them.
+	RetryNonIdempotent bool
+}
+
+// backoff returns how long to wait before the given retry attempt, starting
+// at 1. It grows exponentially, with jitter so that many clients failing at
+// once don't all retry at the same moment.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	min, max := p.MinBackoff, p.MaxBackoff
+	if min <= 0 {
+		min = defaultMinBackoff
+	}
+	if max <= 0 {
+		max = defaultMaxBackoff
+	}
+
+	d := min
+	for i := 1; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	// Wait somewhere between half and all of the computed delay
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func (p *RetryPolicy) maxBackoff() time.Duration {
+	if p.MaxBackoff <= 0 {
+		return defaultMaxBackoff
+	}
+	return p.MaxBackoff
+}
+
+// retryable reports whether a request with the given method should be retried
+// after receiving the given response or error.
+func (p *RetryPolicy) retryable(method string, resp *http.Response, err error) bool {
+	idempotent := method != "POST" || p.RetryNonIdempotent
+	if err != nil {
+		return idempotent || isDialError(err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return idempotent
+	}
+	return false
+}
+
+// isDialError reports whether err happened while establishing a connection,
+// i.e. before any part of the request reached the server.
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// retryAfter parses the Retry-After header on the given response, which is
+// either a number of seconds or an HTTP date.
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := t.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// send makes the given request, retrying it according to the Client's
+// RetryPolicy.
+func (c *Client) send(r *http.Request) (*http.Response, error) {
+	// Use the same settings for every attempt, even if they change meanwhile
+	c.mu.RLock()
+	p, l, send := c.retry, c.limiter, chain(c.middleware, c.client.Do)
+	c.mu.RUnlock()
+
+	if p == nil || p.MaxAttempts < 2 {
+		return sendOnce(r, l, send)
+	}
+
+	ctx := r.Context()
+	for attempt := 1; ; attempt++ {
+		resp, err := sendOnce(r, l, send)
+		if attempt >= p.MaxAttempts || ctx.Err() != nil || !p.retryable(r.Method, resp, err) {
+			return resp, err
+		}
+
+		wait, ok := retryAfter(resp, time.Now())
+		if !ok {
+			wait = p.backoff(attempt)
+		} else if wait > p.maxBackoff() {
+			return resp, err
+		}
+
+		// Give up if the request body can't be rewound. Otherwise, finish
+		// with the previous response before rewinding it, since the
+		// transport may still be reading the old body until then.
+		hasBody := r.Body != nil && r.Body != http.NoBody
+		if hasBody && r.GetBody == nil {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		if hasBody {
+			body, err := r.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r.Body = body
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// sendOnce makes a single attempt at the given request, once the given
+// RateLimiter (if any) allows it.
+func sendOnce(r *http.Request, l *RateLimiter, send SendFunc) (*http.Response, error) {
+	if l != nil {
+		if err := l.Wait(r.Context()); err != nil {
+			return nil, err
+		}
+	}
+	return send(r)
+}
@@ -0,0 +1,259 @@
+package writeas
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newFlakyServer returns a test server that responds to the first `failures`
+// requests with the given status code, then successfully. It also checks
+// that every request it receives has the same body.
+func newFlakyServer(t *testing.T, failures int32, code int, header http.Header) (*httptest.Server, *int32) {
+	var reqs int32
+	var firstBody string
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		n := atomic.AddInt32(&reqs, 1)
+		if n == 1 {
+			firstBody = string(body)
+		} else if string(body) != firstBody {
+			t.Errorf("Request %d body = %q, want %q", n, body, firstBody)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if n <= failures {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(code)
+			fmt.Fprintf(w, `{"code":%d,"error_msg":"Try again."}`, code)
+			return
+		}
+		if r.Method == "POST" {
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"code":201,"data":{"id":"abc","body":"Hi"}}`))
+			return
+		}
+		w.Write([]byte(`{"code":200,"data":{"id":"abc","body":"Hi"}}`))
+	})), &reqs
+}
+
+var fastRetries = &RetryPolicy{
+	MaxAttempts: 3,
+	MinBackoff:  time.Millisecond,
+	MaxBackoff:  10 * time.Millisecond,
+}
+
+func TestRetryTransientErrors(t *testing.T) {
+	srv, reqs := newFlakyServer(t, 2, http.StatusServiceUnavailable, nil)
+	defer srv.Close()
+	c := NewClientWith(Config{URL: srv.URL, Retry: fastRetries})
+
+	if _, err := c.GetPost("abc"); err != nil {
+		t.Fatalf("Expected request to succeed after retries, got: %v", err)
+	}
+	if *reqs != 3 {
+		t.Errorf("Made %d requests, want 3", *reqs)
+	}
+}
+
+// closeTracker is a response body that records whether it was closed.
+type closeTracker struct {
+	io.ReadCloser
+	closed bool
+}
+
+func (b *closeTracker) Close() error {
+	b.closed = true
+	return b.ReadCloser.Close()
+}
+
+func TestRetryClosesResponses(t *testing.T) {
+	srv, _ := newFlakyServer(t, 2, http.StatusTooManyRequests, nil)
+	defer srv.Close()
+	c := NewClientWith(Config{URL: srv.URL, Retry: fastRetries})
+
+	// Check that each failed response is closed before the request is sent
+	// again, with its body rewound
+	var last *closeTracker
+	c.Use(func(next SendFunc) SendFunc {
+		return func(r *http.Request) (*http.Response, error) {
+			if last != nil && !last.closed {
+				t.Errorf("Retried before closing the previous response")
+			}
+			resp, err := next(r)
+			if err == nil {
+				last = &closeTracker{ReadCloser: resp.Body}
+				resp.Body = last
+			}
+			return resp, err
+		}
+	})
+
+	if _, err := c.CreatePost(&PostParams{Content: "Hi"}); err != nil {
+		t.Fatalf("Expected request to succeed after retries, got: %v", err)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	srv, reqs := newFlakyServer(t, 5, http.StatusBadGateway, nil)
+	defer srv.Close()
+	c := NewClientWith(Config{URL: srv.URL, Retry: fastRetries})
+
+	var apiErr *APIError
+	_, err := c.GetPost("abc")
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("Expected 502 APIError, got: %v", err)
+	}
+	if *reqs != 3 {
+		t.Errorf("Made %d requests, want 3", *reqs)
+	}
+}
+
+func TestRetryDisabledByDefault(t *testing.T) {
+	srv, reqs := newFlakyServer(t, 1, http.StatusServiceUnavailable, nil)
+	defer srv.Close()
+	c := NewClientWith(Config{URL: srv.URL})
+
+	if _, err := c.GetPost("abc"); err == nil {
+		t.Fatal("Expected an error without retries")
+	}
+	if *reqs != 1 {
+		t.Errorf("Made %d requests, want 1", *reqs)
+	}
+}
+
+func TestRetryNonIdempotent(t *testing.T) {
+	t.Run("POST not retried after 5xx", func(t *testing.T) {
+		srv, reqs := newFlakyServer(t, 1, http.StatusServiceUnavailable, nil)
+		defer srv.Close()
+		c := NewClientWith(Config{URL: srv.URL, Retry: fastRetries})
+
+		if _, err := c.CreatePost(&PostParams{Content: "Hi"}); err == nil {
+			t.Fatal("Expected an error")
+		}
+		if *reqs != 1 {
+			t.Errorf("Made %d requests, want 1", *reqs)
+		}
+	})
+	t.Run("POST retried after 429", func(t *testing.T) {
+		srv, reqs := newFlakyServer(t, 1, http.StatusTooManyRequests, nil)
+		defer srv.Close()
+		c := NewClientWith(Config{URL: srv.URL, Retry: fastRetries})
+
+		if _, err := c.CreatePost(&PostParams{Content: "Hi"}); err != nil {
+			t.Fatalf("Expected request to succeed after retries, got: %v", err)
+		}
+		if *reqs != 2 {
+			t.Errorf("Made %d requests, want 2", *reqs)
+		}
+	})
+	t.Run("POST retried with RetryNonIdempotent", func(t *testing.T) {
+		srv, reqs := newFlakyServer(t, 1, http.StatusInternalServerError, nil)
+		defer srv.Close()
+		p := *fastRetries
+		p.RetryNonIdempotent = true
+		c := NewClientWith(Config{URL: srv.URL, Retry: &p})
+
+		if _, err := c.CreatePost(&PostParams{Content: "Hi"}); err != nil {
+			t.Fatalf("Expected request to succeed after retries, got: %v", err)
+		}
+		if *reqs != 2 {
+			t.Errorf("Made %d requests, want 2", *reqs)
+		}
+	})
+}
+
+func TestRetryAfter(t *testing.T) {
+	t.Run("honored", func(t *testing.T) {
+		srv, reqs := newFlakyServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"1"}})
+		defer srv.Close()
+		c := NewClientWith(Config{URL: srv.URL, Retry: &RetryPolicy{MaxAttempts: 2, MinBackoff: time.Millisecond}})
+
+		start := time.Now()
+		if _, err := c.GetPost("abc"); err != nil {
+			t.Fatalf("Expected request to succeed after retries, got: %v", err)
+		}
+		if d := time.Since(start); d < time.Second {
+			t.Errorf("Retried after %s, before Retry-After elapsed", d)
+		}
+		if *reqs != 2 {
+			t.Errorf("Made %d requests, want 2", *reqs)
+		}
+	})
+	t.Run("longer than MaxBackoff", func(t *testing.T) {
+		srv, reqs := newFlakyServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"120"}})
+		defer srv.Close()
+		c := NewClientWith(Config{URL: srv.URL, Retry: fastRetries})
+
+		if _, err := c.GetPost("abc"); !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("Expected ErrRateLimited, got: %v", err)
+		}
+		if *reqs != 1 {
+			t.Errorf("Made %d requests, want 1", *reqs)
+		}
+	})
+}
+
+func TestRetryAfterParsing(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{"", 0, false},
+		{"3", 3 * time.Second, true},
+		{"Wed, 01 Jan 2020 00:00:10 GMT", 10 * time.Second, true},
+		{"Tue, 31 Dec 2019 23:59:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+	for _, test := range tests {
+		resp := &http.Response{Header: http.Header{}}
+		if test.header != "" {
+			resp.Header.Set("Retry-After", test.header)
+		}
+		d, ok := retryAfter(resp, now)
+		if d != test.want || ok != test.ok {
+			t.Errorf("retryAfter(%q) = %s, %t; want %s, %t", test.header, d, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	p := &RetryPolicy{MinBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	for attempt, max := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second, time.Second} {
+		for i := 0; i < 20; i++ {
+			d := p.backoff(attempt + 1)
+			if d < max/2 || d > max {
+				t.Fatalf("backoff(%d) = %s, want between %s and %s", attempt+1, d, max/2, max)
+			}
+		}
+	}
+}
+
+func TestRetryDialError(t *testing.T) {
+	// Find an address nothing is listening on
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	_, err = http.Get("http://" + addr)
+	if !isDialError(err) {
+		t.Errorf("Expected dial error, got: %v", err)
+	}
+	if !fastRetries.retryable("POST", nil, err) {
+		t.Errorf("POST should be retried after a dial error")
+	}
+}
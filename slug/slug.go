@@ -0,0 +1,183 @@
+// Package slug derives titles and slugs from post content the way Write.as
+// does, so a post's URL can be known before it's published.
+//
+//	sp := &writeas.PostParams{Content: "# Привет мир\n\n..."}
+//	slug.Plan(sp, existingSlugs) // "privet-mir", or "privet-mir-2" if taken
+//
+// It's kept apart from the writeas package because transliterating titles
+// needs a sizeable table of characters that most API clients don't.
+package slug
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	gosimple "github.com/gosimple/slug"
+	"github.com/writeas/go-writeas/v2"
+	stripmd "github.com/writeas/go-strip-markdown/v2"
+)
+
+const (
+	// maxTitleLen is the longest title derived from a post's body.
+	maxTitleLen = 80
+	// maxSlugLen is the longest slug generated for a post.
+	maxSlugLen = 80
+)
+
+var htmlTagReg = regexp.MustCompile(`<[^>]*>`)
+
+// ExtractTitle splits a title off the start of the given post content, the
+// way Write.as does when a post begins with a "# " heading. If it doesn't,
+// title is empty and body is the whole content.
+func ExtractTitle(content string) (title, body string) {
+	if strings.HasPrefix(content, "# ") {
+		if eol := strings.IndexRune(content, '\n'); eol != -1 {
+			return content[len("# "):eol], strings.TrimLeft(content[eol:], " \t\n\r")
+		}
+	}
+	return "", content
+}
+
+// DeriveTitle returns the title Write.as shows for a post without one: its
+// first line, with Markdown and HTML removed, if that's followed by a blank
+// line and short enough. Otherwise it's the post's first sentence, shortened
+// to a whole number of words with "..." if it's too long.
+func DeriveTitle(content string) string {
+	content = strings.TrimLeftFunc(stripmd.Strip(stripHTML(content)), unicode.IsSpace)
+	eol := strings.IndexRune(content, '\n')
+	blankLine := strings.Index(content, "\n\n")
+	if blankLine != -1 && blankLine <= eol && blankLine <= maxTitleLen {
+		return strings.TrimSpace(content[:blankLine])
+	} else if eol == -1 && utf8.RuneCountInString(content) <= maxTitleLen {
+		return content
+	}
+	title, truncated := truncToWord(postLede(content, true), maxTitleLen)
+	if truncated {
+		title += "..."
+	}
+	return title
+}
+
+// Make returns the slug Write.as generates for a post with the given
+// title, content and language (an ISO 639-1 code, or empty). When title is
+// empty, the slug comes from the start of the content instead. Non-Latin
+// scripts are transliterated, and slugs are limited to 80 characters,
+// ending on a whole word where possible.
+//
+// The result is empty if there's nothing to make a slug from, in which case
+// the server falls back to the post's ID.
+func Make(title, content, lang string) string {
+	if title == "" {
+		body := strings.TrimSpace(stripmd.StripOptions(content, stripmd.Options{SkipImages: true}))
+		title = slugTitle(body)
+	}
+	title = postLede(title, false)
+	title, _ = truncToWord(title, maxSlugLen)
+	s := gosimple.MakeLang(title, lang)
+
+	// Transliteration can make the slug longer, so truncate it again. It
+	// doesn't have any spaces left, so also trim any trailing hyphens.
+	s, _ = truncToWord(s, maxSlugLen)
+	return strings.Trim(s, "-")
+}
+
+// Unique returns base if it isn't in taken, or otherwise the first of
+// base-2, base-3, etc. that isn't.
+//
+// Write.as picks a random suffix for a slug that's already used in a
+// collection, so to know a post's URL ahead of time, set
+// writeas.PostParams.Slug to an unused slug like this one.
+func Unique(base string, taken []string) string {
+	used := make(map[string]bool, len(taken))
+	for _, s := range taken {
+		used[s] = true
+	}
+	if !used[base] {
+		return base
+	}
+	for i := 2; ; i++ {
+		s := base + "-" + strconv.Itoa(i)
+		if !used[s] {
+			return s
+		}
+	}
+}
+
+// Plan sets sp.Slug, if it's empty, to the slug the post would get from its
+// title or content, and makes it unique among the given slugs already used
+// in the collection. It returns the planned slug.
+func Plan(sp *writeas.PostParams, taken []string) string {
+	s := sp.Slug
+	if s == "" {
+		lang := ""
+		if sp.Language != nil {
+			lang = *sp.Language
+		}
+		s = Make(sp.Title, sp.Content, lang)
+	}
+	if s != "" {
+		s = Unique(s, taken)
+	}
+	sp.Slug = s
+	return s
+}
+
+// slugTitle returns the text that a slug is made from when a post has no
+// title: its first line if that's followed by a blank line, or else the
+// whole post, which is then cut down to its first sentence.
+func slugTitle(content string) string {
+	content = strings.TrimLeftFunc(stripmd.Strip(stripHTML(content)), unicode.IsSpace)
+	eol := strings.IndexRune(content, '\n')
+	blankLine := strings.Index(content, "\n\n")
+	if blankLine != -1 && blankLine <= eol && blankLine <= maxTitleLen {
+		return strings.TrimSpace(content[:blankLine])
+	}
+	return content
+}
+
+// postLede returns the first line or sentence of t, including the
+// punctuation that ends it if includePunc is set.
+func postLede(t string, includePunc bool) string {
+	adj := 0
+	if includePunc {
+		adj = 1
+	}
+	if nl := strings.IndexRune(t, '\n'); nl != -1 {
+		t = t[:nl]
+	}
+	if i := strings.Index(t, ". "); i != -1 {
+		t = t[:i+adj]
+	}
+	for _, p := range []rune{'。', '?', '？'} {
+		if i := strings.IndexRune(t, p); i != -1 {
+			if includePunc {
+				i += utf8.RuneLen(p)
+			}
+			t = t[:i]
+		}
+	}
+	return t
+}
+
+// truncToWord shortens s to at most l characters, cutting it at the last
+// space if there is one, and reports whether it was shortened.
+func truncToWord(s string, l int) (string, bool) {
+	c := []rune(s)
+	if len(c) <= l {
+		return s, false
+	}
+	s = string(c[:l])
+	if i := strings.LastIndexByte(s, ' '); i != -1 {
+		s = s[:i]
+	}
+	return s, true
+}
+
+// stripHTML removes all HTML tags from s, leaving their text unescaped.
+func stripHTML(s string) string {
+	return html.UnescapeString(htmlTagReg.ReplaceAllString(s, ""))
+}
@@ -0,0 +1,108 @@
+package slug
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/writeas/go-writeas/v2"
+	"github.com/writeas/go-writeas/v2/writeastest"
+)
+
+func TestExtractTitle(t *testing.T) {
+	tests := []struct {
+		in, title, body string
+	}{
+		{"# Title\n\nBody.", "Title", "Body."},
+		{"# Only a heading", "", "# Only a heading"},
+		{"## Subheading\n\nBody.", "", "## Subheading\n\nBody."},
+		{"No title.\n\nBody.", "", "No title.\n\nBody."},
+	}
+	for _, test := range tests {
+		title, body := ExtractTitle(test.in)
+		if title != test.title || body != test.body {
+			t.Errorf("ExtractTitle(%q) = %q, %q; want %q, %q", test.in, title, body, test.title, test.body)
+		}
+	}
+}
+
+func TestDeriveTitle(t *testing.T) {
+	long := "A very long first line that keeps going on and on and on, well beyond the limit for titles"
+	tests := []struct {
+		in, want string
+	}{
+		{"# Heading\n\nBody.", "Heading"},
+		{"One line only", "One line only"},
+		{"First sentence. Second sentence\non two lines.", "First sentence."},
+		{"<b>Bold</b> &amp; *emphasized*\n\nBody.", "Bold & emphasized"},
+		{long, "A very long first line that keeps going on and on and on, well beyond the limit..."},
+	}
+	for _, test := range tests {
+		if got := DeriveTitle(test.in); got != test.want {
+			t.Errorf("DeriveTitle(%q) = %q, want %q", test.in, got, test.want)
+		}
+	}
+}
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		title, content, lang string
+		want                 string
+	}{
+		{"Hello, World!", "", "", "hello-world"},
+		{"", "# My First Post\n\nSome text.", "", "my-first-post"},
+		{"", "Two sentences. In one line.", "", "two-sentences"},
+		{"", "What is this? Something.", "", "what-is-this"},
+		{"", "![Image](https://i.snap.as/a.png)\n\nA [link](https://write.as) here", "", "a-link-here"},
+		{"Tom & Jerry @ home", "", "", "tom-and-jerry-at-home"},
+		{"Über & Straße", "", "de", "ueber-und-strasse"},
+		{"你好，世界", "", "", "ni-hao-shi-jie"},
+		{"Привет мир", "", "", "privet-mir"},
+		{"🎉", "", "", ""},
+	}
+	for _, test := range tests {
+		if got := Make(test.title, test.content, test.lang); got != test.want {
+			t.Errorf("Make(%q, %q, %q) = %q, want %q", test.title, test.content, test.lang, got, test.want)
+		}
+	}
+
+	long := Make(strings.Repeat("word ", 30), "", "")
+	if len(long) > maxSlugLen || strings.HasSuffix(long, "-") || !strings.HasSuffix(long, "word") {
+		t.Errorf("Long slug not truncated to a whole word: %q", long)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	taken := []string{"hello", "hello-2"}
+	sp := &writeas.PostParams{Title: "Hello"}
+	if got := Plan(sp, taken); got != "hello-3" || sp.Slug != "hello-3" {
+		t.Errorf("Planned %q, slug %q; want hello-3", got, sp.Slug)
+	}
+
+	sp = &writeas.PostParams{Slug: "custom", Title: "Hello"}
+	if got := Plan(sp, taken); got != "custom" {
+		t.Errorf("Planned %q, want custom", got)
+	}
+}
+
+func TestPlannedSlugIsUsed(t *testing.T) {
+	srv := writeastest.NewServer()
+	defer srv.Close()
+	srv.AddUser("demo", "demo")
+	srv.AddCollection("demo", "tester", "Tester")
+	srv.AddCollectionPost("tester", "First post", "This is the first post.")
+	srv.AddCollectionPost("tester", "Second post", "This is the second post.")
+	c := writeas.NewClientWith(writeas.Config{URL: srv.APIURL()})
+	if _, err := c.LogIn("demo", "demo"); err != nil {
+		t.Fatalf("Unable to log in: %v", err)
+	}
+
+	sp := &writeas.PostParams{Content: "First post\n\nAnother post with the same title.", Collection: "tester"}
+	slug := Plan(sp, []string{"first-post", "second-post"})
+	p, err := c.CreatePost(sp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Slug != slug {
+		t.Errorf("Created post with slug %q, planned %q", p.Slug, slug)
+	}
+}
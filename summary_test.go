@@ -0,0 +1,91 @@
+package writeas
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPostExcerpt(t *testing.T) {
+	p := &Post{Content: "# Title\n\nSome **bold** text with a [link](https://write.as) and a note.[^1]\n\n<!--more-->\n\nThe rest.\n\n[^1]: A footnote."}
+	if got, want := p.Excerpt(0), "Title Some bold text with a link and a note."; got != want {
+		t.Errorf("Got %q, want %q", got, want)
+	}
+
+	p = &Post{Content: "One two three four five six."}
+	tests := []struct {
+		maxLen int
+		want   string
+	}{
+		{100, "One two three four five six."},
+		{14, "One two three…"},
+		{12, "One two…"},
+		{3, "On…"},
+	}
+	for _, test := range tests {
+		if got := p.Excerpt(test.maxLen); got != test.want {
+			t.Errorf("Excerpt(%d) = %q, want %q", test.maxLen, got, test.want)
+		}
+	}
+}
+
+func TestPostWordCount(t *testing.T) {
+	tests := []struct {
+		body string
+		want int
+	}{
+		{"", 0},
+		{"It's a *well-known* fact — [really](https://example.com).", 5},
+		{"```\ncode counts too\n```", 3},
+		{"日本語のテキスト", 8},
+		{"Mixed 中文 text", 4},
+	}
+	for _, test := range tests {
+		p := &Post{Content: test.body}
+		if got := p.WordCount(); got != test.want {
+			t.Errorf("WordCount(%q) = %d, want %d", test.body, got, test.want)
+		}
+	}
+}
+
+func TestPostReadingTime(t *testing.T) {
+	en, ar, ja := "en-US", "ar", "ja"
+	long := strings.Repeat("word ", 500)
+	tests := []struct {
+		name string
+		body string
+		lang *string
+		want time.Duration
+	}{
+		{"empty", "", nil, 0},
+		{"short", "Just a few words.", nil, time.Minute},
+		{"default", long, nil, 3 * time.Minute},
+		{"english", long, &en, 3 * time.Minute},
+		{"arabic", long, &ar, 4 * time.Minute},
+		{"japanese", strings.Repeat("日本語", 250), &ja, 3 * time.Minute},
+		{"chinese", strings.Repeat("中文字", 250), nil, 3 * time.Minute},
+	}
+	for _, test := range tests {
+		p := &Post{Content: test.body, Language: test.lang}
+		if got := p.ReadingTime(); got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestPostFirstImage(t *testing.T) {
+	tests := []struct {
+		post Post
+		want string
+	}{
+		{Post{Content: "No images."}, ""},
+		{Post{Images: []string{"https://i.snap.as/a.png"}, Content: "![b](https://i.snap.as/b.png)"}, "https://i.snap.as/a.png"},
+		{Post{Content: "Text\n\n![alt text](https://i.snap.as/b.png \"Title\")\n\n<img src=\"https://i.snap.as/c.png\">"}, "https://i.snap.as/b.png"},
+		{Post{Content: "<img alt=\"\" src='https://i.snap.as/c.png'> ![](https://i.snap.as/b.png)"}, "https://i.snap.as/c.png"},
+	}
+	for _, test := range tests {
+		if got := test.post.FirstImage(); got != test.want {
+			t.Errorf("FirstImage() for %q = %q, want %q", test.post.Content, got, test.want)
+		}
+	}
+}
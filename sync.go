@@ -0,0 +1,511 @@
+package writeas
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultSyncState is the name of the sync state file kept in the synced
+// directory, unless SyncOptions.StatePath is set.
+const defaultSyncState = ".writeas-sync.json"
+
+// SyncOp is an operation Sync performs to bring a local file and a post back
+// in line.
+type SyncOp int
+
+const (
+	// SyncCreate publishes a new local file as a post.
+	SyncCreate SyncOp = iota
+	// SyncUpdate updates a post with its changed local file.
+	SyncUpdate
+	// SyncPull writes a new or changed post to its local file.
+	SyncPull
+	// SyncDeleteRemote deletes a post whose local file was deleted.
+	SyncDeleteRemote
+	// SyncDeleteLocal deletes a local file whose post was deleted.
+	SyncDeleteLocal
+	// SyncConflict reports a file and post that both changed since the last
+	// sync. Sync leaves both alone; resolve it by changing or deleting
+	// either one so they match.
+	SyncConflict
+)
+
+var syncOpNames = []string{"create", "update", "pull", "delete-remote", "delete-local", "conflict"}
+
+func (op SyncOp) String() string {
+	if op < 0 || int(op) >= len(syncOpNames) {
+		return fmt.Sprintf("SyncOp(%d)", int(op))
+	}
+	return syncOpNames[op]
+}
+
+// SyncAction is a single planned or performed sync operation.
+type SyncAction struct {
+	Op SyncOp
+	// Path is the local file, relative to the synced directory, with
+	// forward slashes.
+	Path string
+	// ID and Slug identify the post, if there is one yet.
+	ID   string
+	Slug string
+	// Err is why the action failed, once Sync has tried it, or why it
+	// can't be done at all.
+	Err error
+}
+
+// SyncPlan lists the actions Sync performs, or would perform in a dry run.
+type SyncPlan struct {
+	Actions []SyncAction
+}
+
+// Conflicts returns the actions that are conflicts.
+func (p *SyncPlan) Conflicts() []SyncAction {
+	var cs []SyncAction
+	for _, a := range p.Actions {
+		if a.Op == SyncConflict {
+			cs = append(cs, a)
+		}
+	}
+	return cs
+}
+
+// String returns the plan as one line per action, suitable for showing
+// before a sync.
+func (p *SyncPlan) String() string {
+	var b strings.Builder
+	for _, a := range p.Actions {
+		fmt.Fprintf(&b, "%-13s %s", a.Op, a.Path)
+		if a.Slug != "" {
+			fmt.Fprintf(&b, " (%s)", a.Slug)
+		}
+		if a.Err != nil {
+			fmt.Fprintf(&b, ": %v", a.Err)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// SyncOptions configures Sync.
+type SyncOptions struct {
+	// StatePath is the file where Sync records the state of each post as
+	// of the last sync. Defaults to .writeas-sync.json in the synced
+	// directory.
+	StatePath string
+
+	// DryRun only plans the sync, without changing anything.
+	DryRun bool
+}
+
+// syncState is the persisted state of every synced post, as of the last
+// sync.
+type syncState struct {
+	Collection string                    `json:"collection"`
+	Files      map[string]*syncStateFile `json:"files"`
+}
+
+type syncStateFile struct {
+	ID   string `json:"id"`
+	Slug string `json:"slug"`
+	// Hash is the content hash both sides had.
+	Hash string `json:"hash"`
+	// Updated is the post's Updated time.
+	Updated time.Time `json:"updated"`
+}
+
+// syncItem is a local file and the post it corresponds to, either of which
+// might not exist.
+type syncItem struct {
+	path   string
+	file   *MarkdownPost
+	raw    []byte
+	post   *Post
+	state  *syncStateFile
+	action *SyncAction
+	// err is why nothing can be done with the item
+	err error
+}
+
+// Sync brings the Markdown files in dir and the posts in a collection in
+// line with each other, in both directions. Local files are matched with
+// posts by the ID in their front matter, or otherwise by slug, taken from
+// their front matter or file name. Whether each side changed since the last
+// sync is decided by content hash for files, and by Post.Updated for posts.
+//
+//   - New or changed files are published or update their posts, and deleted
+//     files delete their posts.
+//   - New or changed posts are written to files, named after their slug for
+//     new ones, and deleted posts delete their files.
+//   - If both a file and its post changed, or on the first sync they differ,
+//     it's a conflict, and neither is touched.
+//
+// Sync returns the plan of actions, each with any error that occurred, along
+// with the first such error. With opts.DryRun, the plan is returned without
+// performing it, e.g. to show it for confirmation first.
+func (c *Client) Sync(ctx context.Context, dir, alias string, opts *SyncOptions) (*SyncPlan, error) {
+	if opts == nil {
+		opts = &SyncOptions{}
+	}
+	statePath := opts.StatePath
+	if statePath == "" {
+		statePath = filepath.Join(dir, defaultSyncState)
+	}
+
+	state, err := loadSyncState(statePath, alias)
+	if err != nil {
+		return nil, err
+	}
+	items, err := c.syncItems(ctx, dir, alias, state)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, it := range items {
+		planSync(it)
+	}
+	if opts.DryRun {
+		return syncPlan(items), nil
+	}
+
+	var firstErr error
+	for _, it := range items {
+		if err := ctx.Err(); err != nil {
+			firstErr = err
+			break
+		}
+		if err := c.applySync(ctx, dir, alias, state, it); err != nil {
+			it.action.Err = err
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	// Save whatever was done, even if some actions failed
+	if err := saveSyncState(statePath, state); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return syncPlan(items), firstErr
+}
+
+func syncPlan(items []*syncItem) *SyncPlan {
+	plan := &SyncPlan{}
+	for _, it := range items {
+		if it.action != nil {
+			plan.Actions = append(plan.Actions, *it.action)
+		}
+	}
+	return plan
+}
+
+// syncItems pairs up local files with posts.
+func (c *Client) syncItems(ctx context.Context, dir, alias string, state *syncState) ([]*syncItem, error) {
+	var posts []Post
+	it := c.CollectionPosts(ctx, alias)
+	for it.Next() {
+		posts = append(posts, *it.Post())
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+	byID := map[string]*Post{}
+	bySlug := map[string]*Post{}
+	for i := range posts {
+		byID[posts[i].ID] = &posts[i]
+		bySlug[posts[i].Slug] = &posts[i]
+	}
+	stateByID := map[string]*syncStateFile{}
+	for _, st := range state.Files {
+		stateByID[st.ID] = st
+	}
+	matched := map[string]bool{}
+	items := map[string]*syncItem{}
+
+	err := filepath.Walk(dir, func(fn string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() && fn != dir && strings.HasPrefix(fi.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if fi.IsDir() || !isMarkdownFile(fn) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, fn)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		b, err := ioutil.ReadFile(fn)
+		if err != nil {
+			return err
+		}
+		mp, err := ParseMarkdownPost(b)
+		if err != nil {
+			return fmt.Errorf("%s: %v", rel, err)
+		}
+
+		item := &syncItem{path: rel, file: mp, raw: b, state: state.Files[rel]}
+		id := mp.ID
+		if item.state != nil {
+			id = item.state.ID
+		} else if id != "" {
+			// The file may have been renamed since the last sync
+			item.state = stateByID[id]
+		}
+		if id != "" {
+			item.post = byID[id]
+		} else {
+			slug := mp.Slug
+			if slug == "" {
+				slug = strings.TrimSuffix(path.Base(rel), path.Ext(rel))
+			}
+			item.post = bySlug[slug]
+		}
+		if item.post != nil {
+			matched[item.post.ID] = true
+		}
+		items[rel] = item
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Files deleted since the last sync
+	for rel, st := range state.Files {
+		if items[rel] != nil {
+			continue
+		}
+		if matched[st.ID] {
+			// Renamed, so just forget the old name
+			items[rel] = &syncItem{path: rel}
+			continue
+		}
+		items[rel] = &syncItem{path: rel, post: byID[st.ID], state: st}
+		matched[st.ID] = true
+	}
+	// A post missing from the listing may only have been cut off from it, so
+	// make sure it's really gone before its file is deleted
+	for _, it := range items {
+		if it.state == nil || it.post != nil || it.file == nil {
+			continue
+		}
+		p, err := c.GetPostContext(ctx, it.state.ID)
+		if err == nil {
+			it.post = p
+			matched[p.ID] = true
+		} else if !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrGone) {
+			return nil, err
+		}
+	}
+	// New posts
+	for i := range posts {
+		p := &posts[i]
+		if matched[p.ID] {
+			continue
+		}
+		name := p.Slug
+		if name == "" {
+			name = p.ID
+		}
+		rel := name + ".md"
+		if !safePathComponent(name) {
+			// Never let the server pick a path outside of dir
+			rel = p.ID + ".md"
+			items[rel] = &syncItem{path: rel, post: p, err: fmt.Errorf("unsafe slug %q", name)}
+			continue
+		}
+		if other := items[rel]; other != nil {
+			// A file that didn't match any post already has this name
+			other.post = p
+			continue
+		}
+		items[rel] = &syncItem{path: rel, post: p}
+	}
+
+	sorted := make([]*syncItem, 0, len(items))
+	for _, it := range items {
+		sorted = append(sorted, it)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].path < sorted[j].path })
+	return sorted, nil
+}
+
+// planSync decides what to do with the given item, setting its action.
+func planSync(it *syncItem) {
+	var fileHash, postHash string
+	if it.file != nil {
+		fileHash = contentHash(it.file.Title, it.file.Content)
+	}
+	if it.post != nil {
+		postHash = contentHash(it.post.Title, it.post.Content)
+	}
+	act := func(op SyncOp) {
+		it.action = &SyncAction{Op: op, Path: it.path}
+		if it.post != nil {
+			it.action.ID, it.action.Slug = it.post.ID, it.post.Slug
+		} else if it.state != nil {
+			it.action.ID, it.action.Slug = it.state.ID, it.state.Slug
+		}
+	}
+
+	if it.err != nil {
+		act(SyncPull)
+		it.action.Err = it.err
+		return
+	}
+	if it.file != nil && it.post != nil && fileHash == postHash {
+		// Already the same, whatever happened since the last sync
+		return
+	}
+	if it.state == nil {
+		switch {
+		case it.file != nil && it.post != nil:
+			act(SyncConflict)
+		case it.file != nil:
+			act(SyncCreate)
+		case it.post != nil:
+			act(SyncPull)
+		}
+		return
+	}
+
+	fileChanged := it.file == nil || fileHash != it.state.Hash
+	postChanged := it.post == nil || it.post.Updated.After(it.state.Updated)
+	switch {
+	case it.file == nil && it.post == nil:
+		// Deleted on both sides
+	case fileChanged && postChanged:
+		act(SyncConflict)
+	case it.file == nil:
+		act(SyncDeleteRemote)
+	case it.post == nil:
+		act(SyncDeleteLocal)
+	case fileChanged:
+		act(SyncUpdate)
+	case postChanged:
+		act(SyncPull)
+	}
+}
+
+// applySync performs the item's action, if any, and records the resulting
+// state.
+func (c *Client) applySync(ctx context.Context, dir, alias string, state *syncState, it *syncItem) error {
+	if it.err != nil {
+		return it.err
+	}
+	fn, err := joinWithin(dir, it.path)
+	if err != nil {
+		return err
+	}
+	record := func(p *Post) {
+		state.Files[it.path] = &syncStateFile{
+			ID:      p.ID,
+			Slug:    p.Slug,
+			Hash:    contentHash(p.Title, p.Content),
+			Updated: p.Updated,
+		}
+	}
+
+	if it.action == nil {
+		if it.file != nil && it.post != nil {
+			record(it.post)
+		} else if it.file == nil && it.post == nil {
+			delete(state.Files, it.path)
+		}
+		return nil
+	}
+
+	switch it.action.Op {
+	case SyncCreate, SyncUpdate:
+		sp := it.file.Params()
+		sp.Collection = alias
+		var p *Post
+		var err error
+		if it.action.Op == SyncCreate {
+			p, err = c.CreatePostContext(ctx, sp)
+		} else {
+			// The file's updated time is from when it was last pulled, so
+			// let the server record this update as happening now
+			sp.Updated = nil
+			p, err = c.UpdatePostContext(ctx, it.post.ID, "", sp)
+		}
+		if err != nil {
+			return err
+		}
+		it.action.ID, it.action.Slug = p.ID, p.Slug
+		record(p)
+		if it.file.ID != p.ID || it.file.Slug != p.Slug {
+			return writePostRef(fn, it.raw, postRef{ID: p.ID, Slug: p.Slug})
+		}
+	case SyncPull:
+		if err := os.MkdirAll(filepath.Dir(fn), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(fn, it.post.markdown(alias), 0644); err != nil {
+			return err
+		}
+		record(it.post)
+	case SyncDeleteRemote:
+		if err := c.DeletePostContext(ctx, it.state.ID, ""); err != nil {
+			return err
+		}
+		delete(state.Files, it.path)
+	case SyncDeleteLocal:
+		if err := os.Remove(fn); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		delete(state.Files, it.path)
+	}
+	return nil
+}
+
+// contentHash returns a hash of a post's title and body, ignoring
+// differences in line endings and surrounding whitespace.
+func contentHash(title, body string) string {
+	body = strings.Replace(body, "\r\n", "\n", -1)
+	h := sha256.New()
+	h.Write([]byte(strings.TrimSpace(title)))
+	h.Write([]byte{0})
+	h.Write([]byte(strings.TrimSpace(body)))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func loadSyncState(fn, alias string) (*syncState, error) {
+	state := &syncState{Collection: alias, Files: map[string]*syncStateFile{}}
+	b, err := ioutil.ReadFile(fn)
+	if os.IsNotExist(err) {
+		return state, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, state); err != nil {
+		return nil, fmt.Errorf("parse sync state: %v", err)
+	}
+	if state.Collection != alias {
+		return nil, fmt.Errorf("sync state %s is for collection %q, not %q", fn, state.Collection, alias)
+	}
+	if state.Files == nil {
+		state.Files = map[string]*syncStateFile{}
+	}
+	return state, nil
+}
+
+func saveSyncState(fn string, state *syncState) error {
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(fn, append(b, '\n'))
+}
@@ -0,0 +1,247 @@
+package writeas
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func syncOps(plan *SyncPlan) map[string]SyncOp {
+	ops := map[string]SyncOp{}
+	for _, a := range plan.Actions {
+		ops[a.Path] = a.Op
+	}
+	return ops
+}
+
+func TestSync(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	c := NewClientWith(Config{URL: srv.APIURL()})
+	if _, err := c.LogIn("demo", "demo"); err != nil {
+		t.Fatalf("Unable to log in: %v", err)
+	}
+	ctx := context.Background()
+
+	dir := writeTestFiles(t, map[string]string{
+		"local.md":      "---\ntitle: Local\n---\n\nWritten offline.\n",
+		"first-post.md": "---\ntitle: First post\n---\n\nThis is the first post.\n",
+	})
+	defer os.RemoveAll(dir)
+	statePath := filepath.Join(dir, defaultSyncState)
+
+	plan, err := c.Sync(ctx, dir, "tester", &SyncOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	want := map[string]SyncOp{"local.md": SyncCreate, "second-post.md": SyncPull}
+	if got := syncOps(plan); !reflect.DeepEqual(got, want) {
+		t.Errorf("Dry run planned %v, want %v", got, want)
+	}
+	if !strings.Contains(plan.String(), "create        local.md\n") {
+		t.Errorf("Unexpected plan output:\n%s", plan)
+	}
+	if _, err := os.Stat(statePath); !os.IsNotExist(err) {
+		t.Errorf("Dry run wrote sync state")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "second-post.md")); !os.IsNotExist(err) {
+		t.Errorf("Dry run pulled a post")
+	}
+
+	if _, err := c.Sync(ctx, dir, "tester", nil); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	local, err := ioutil.ReadFile(filepath.Join(dir, "local.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mp, _ := ParseMarkdownPost(local)
+	if mp.ID == "" {
+		t.Fatalf("Created post ID not written to local.md:\n%s", local)
+	}
+	if b, err := ioutil.ReadFile(filepath.Join(dir, "second-post.md")); err != nil || !strings.Contains(string(b), "This is the second post.") {
+		t.Errorf("second-post.md not pulled: %v\n%s", err, b)
+	}
+
+	plan, err = c.Sync(ctx, dir, "tester", nil)
+	if err != nil {
+		t.Fatalf("Second sync failed: %v", err)
+	}
+	if len(plan.Actions) != 0 {
+		t.Errorf("Expected nothing to do after syncing, got:\n%s", plan)
+	}
+
+	// Change things on both sides
+	later := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	first, err := c.GetCollectionPost("tester", "first-post")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.UpdatePost(first.ID, "", &PostParams{Title: "First post", Content: "Edited online.", Updated: &later}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.UpdatePost(mp.ID, "", &PostParams{Title: "Local", Content: "Edited online too.", Updated: &later}); err != nil {
+		t.Fatal(err)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "local.md"), []byte(strings.Replace(string(local), "Written offline.", "Edited offline.", 1)), 0644)
+	os.Remove(filepath.Join(dir, "second-post.md"))
+	srv.AddCollectionPost("tester", "Third post", "Brand new.")
+
+	plan, err = c.Sync(ctx, dir, "tester", nil)
+	if err != nil {
+		t.Fatalf("Sync with changes failed: %v", err)
+	}
+	want = map[string]SyncOp{
+		"first-post.md":  SyncPull,
+		"local.md":       SyncConflict,
+		"second-post.md": SyncDeleteRemote,
+		"third-post.md":  SyncPull,
+	}
+	if got := syncOps(plan); !reflect.DeepEqual(got, want) {
+		t.Errorf("Planned %v, want %v", got, want)
+	}
+	if len(plan.Conflicts()) != 1 {
+		t.Errorf("Expected 1 conflict, got %v", plan.Conflicts())
+	}
+	if b, _ := ioutil.ReadFile(filepath.Join(dir, "first-post.md")); !strings.Contains(string(b), "Edited online.") {
+		t.Errorf("first-post.md not updated:\n%s", b)
+	}
+	if _, err := c.GetCollectionPost("tester", "second-post"); err == nil {
+		t.Errorf("second-post wasn't deleted")
+	}
+
+	// Resolve the conflict by taking the local version
+	if _, err := c.UpdatePost(mp.ID, "", &PostParams{Title: "Local", Content: "Edited offline.\n"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DeleteCollectionPost("tester", "first-post"); err != nil {
+		t.Fatal(err)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "third-post.md"), []byte("---\ntitle: Third post\n---\n\nChanged locally.\n"), 0644)
+
+	plan, err = c.Sync(ctx, dir, "tester", nil)
+	if err != nil {
+		t.Fatalf("Final sync failed: %v", err)
+	}
+	want = map[string]SyncOp{
+		"first-post.md": SyncDeleteLocal,
+		"third-post.md": SyncUpdate,
+	}
+	if got := syncOps(plan); !reflect.DeepEqual(got, want) {
+		t.Errorf("Planned %v, want %v", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "first-post.md")); !os.IsNotExist(err) {
+		t.Errorf("first-post.md wasn't deleted")
+	}
+
+	t.Run("renamed file", func(t *testing.T) {
+		if err := os.Rename(filepath.Join(dir, "third-post.md"), filepath.Join(dir, "renamed.md")); err != nil {
+			t.Fatal(err)
+		}
+		plan, err := c.Sync(ctx, dir, "tester", nil)
+		if err != nil {
+			t.Fatalf("Sync failed: %v", err)
+		}
+		if len(plan.Actions) != 0 {
+			t.Errorf("Expected nothing to do after renaming, got:\n%s", plan)
+		}
+		if _, err := c.GetCollectionPost("tester", "third-post"); err != nil {
+			t.Errorf("Renaming the file affected its post: %v", err)
+		}
+	})
+
+	t.Run("wrong collection", func(t *testing.T) {
+		if _, err := c.Sync(ctx, dir, "other", &SyncOptions{DryRun: true}); err == nil {
+			t.Errorf("Expected error using another collection's sync state")
+		}
+	})
+}
+
+// tamperPostListing returns Middleware that edits the posts listed in a
+// collection's posts responses.
+func tamperPostListing(edit func(posts []interface{}) []interface{}) Middleware {
+	return func(next SendFunc) SendFunc {
+		return func(r *http.Request) (*http.Response, error) {
+			resp, err := next(r)
+			if err != nil || r.Method != "GET" || !strings.HasSuffix(r.URL.Path, "/posts") || !strings.Contains(r.URL.Path, "/collections/") {
+				return resp, err
+			}
+			var env map[string]interface{}
+			b, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err := json.Unmarshal(b, &env); err == nil {
+				if data, ok := env["data"].(map[string]interface{}); ok {
+					posts, _ := data["posts"].([]interface{})
+					data["posts"] = edit(posts)
+					b, _ = json.Marshal(env)
+				}
+			}
+			resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+			resp.ContentLength = int64(len(b))
+			resp.Header.Del("Content-Length")
+			return resp, nil
+		}
+	}
+}
+
+func TestSyncUntrustedListing(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	c := NewClientWith(Config{URL: srv.APIURL()})
+	if _, err := c.LogIn("demo", "demo"); err != nil {
+		t.Fatalf("Unable to log in: %v", err)
+	}
+	parent, err := ioutil.TempDir("", "writeas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(parent)
+	dir := filepath.Join(parent, "posts")
+	os.Mkdir(dir, 0755)
+	ctx := context.Background()
+
+	if _, err := c.Sync(ctx, dir, "tester", nil); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+
+	// Leave second-post out of the listing, as if it was cut off by pages
+	// shifting, and add a post with a slug that would escape dir
+	c.Use(tamperPostListing(func(posts []interface{}) []interface{} {
+		var out []interface{}
+		for _, p := range posts {
+			p := p.(map[string]interface{})
+			if p["slug"] == "second-post" {
+				continue
+			}
+			out = append(out, p)
+		}
+		return append(out, map[string]interface{}{"id": "evil", "slug": "../evil", "body": "Gotcha"})
+	}))
+
+	plan, err := c.Sync(ctx, dir, "tester", nil)
+	if err == nil {
+		t.Errorf("Expected error for unsafe slug")
+	}
+	for _, a := range plan.Actions {
+		if a.ID == "evil" && a.Err == nil {
+			t.Errorf("Action for unsafe slug has no error: %+v", a)
+		}
+		if a.Op == SyncDeleteLocal {
+			t.Errorf("Planned to delete a file whose post still exists: %+v", a)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "second-post.md")); err != nil {
+		t.Errorf("second-post.md was deleted: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(parent, "evil.md")); !os.IsNotExist(err) {
+		t.Errorf("Post written outside of the sync directory")
+	}
+}
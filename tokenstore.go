@@ -0,0 +1,143 @@
+package writeas
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// TokenStore keeps the tokens of anonymous posts, which the API only returns
+// once, when the post is created. Set one on a Client with
+// Config.TokenStore or SetTokenStore to have it record the token of every
+// anonymous post the Client creates, and supply it to UpdatePost and
+// DeletePost when no token is given.
+//
+// Implementations must be safe for concurrent use.
+type TokenStore interface {
+	// Get returns the token for the post with the given ID, or an empty
+	// string if there isn't one.
+	Get(id string) (string, error)
+	// Put stores the token for the post with the given ID, replacing any
+	// existing one.
+	Put(id, token string) error
+	// Delete removes the token for the post with the given ID, if there is
+	// one.
+	Delete(id string) error
+}
+
+// FileTokenStore is a TokenStore that keeps tokens in a JSON file, as a list
+// of OwnedPostParams. The file is only readable by its owner, and is locked
+// while it's being read or changed, so several processes can share it. (On
+// platforms without file locking, only access from within the same process
+// is synchronized.)
+type FileTokenStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileTokenStore returns a FileTokenStore that keeps tokens in the file at
+// path. The file is created when the first token is stored.
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{path: path}
+}
+
+// Get implements TokenStore.
+func (s *FileTokenStore) Get(id string) (string, error) {
+	token := ""
+	err := s.withLock(func() error {
+		posts, err := s.read()
+		if err != nil {
+			return err
+		}
+		for _, p := range posts {
+			if p.ID == id {
+				token = p.Token
+			}
+		}
+		return nil
+	})
+	return token, err
+}
+
+// Put implements TokenStore.
+func (s *FileTokenStore) Put(id, token string) error {
+	return s.update(func(posts []OwnedPostParams) []OwnedPostParams {
+		for i := range posts {
+			if posts[i].ID == id {
+				posts[i].Token = token
+				return posts
+			}
+		}
+		return append(posts, OwnedPostParams{ID: id, Token: token})
+	})
+}
+
+// Delete implements TokenStore.
+func (s *FileTokenStore) Delete(id string) error {
+	return s.update(func(posts []OwnedPostParams) []OwnedPostParams {
+		out := posts[:0]
+		for _, p := range posts {
+			if p.ID != id {
+				out = append(out, p)
+			}
+		}
+		return out
+	})
+}
+
+// All returns every stored post ID and token, in the order they were first
+// stored.
+func (s *FileTokenStore) All() ([]OwnedPostParams, error) {
+	var posts []OwnedPostParams
+	err := s.withLock(func() error {
+		var err error
+		posts, err = s.read()
+		return err
+	})
+	return posts, err
+}
+
+// update replaces the stored posts with the result of fn.
+func (s *FileTokenStore) update(fn func([]OwnedPostParams) []OwnedPostParams) error {
+	return s.withLock(func() error {
+		posts, err := s.read()
+		if err != nil {
+			return err
+		}
+		return s.write(fn(posts))
+	})
+}
+
+// withLock calls fn while holding both the in-process lock and the lock on
+// the store's file.
+func (s *FileTokenStore) withLock(fn func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return withFileLock(s.path, fn)
+}
+
+func (s *FileTokenStore) read() ([]OwnedPostParams, error) {
+	b, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return []OwnedPostParams{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	posts := []OwnedPostParams{}
+	if len(b) == 0 {
+		return posts, nil
+	}
+	if err := json.Unmarshal(b, &posts); err != nil {
+		return nil, err
+	}
+	return posts, nil
+}
+
+func (s *FileTokenStore) write(posts []OwnedPostParams) error {
+	b, err := json.MarshalIndent(posts, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, append(b, '\n'))
+}
@@ -0,0 +1,122 @@
+package writeas
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestFileTokenStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "writeas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tokens.json")
+	s := NewFileTokenStore(path)
+
+	if tok, err := s.Get("missing"); err != nil || tok != "" {
+		t.Fatalf("Get on empty store = %q, %v", tok, err)
+	}
+
+	if err := s.Put("a", "token-a"); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := s.Put("b", "token-b"); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := s.Put("a", "token-a2"); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if tok, err := s.Get("a"); err != nil || tok != "token-a2" {
+		t.Errorf("Get(a) = %q, %v; want token-a2", tok, err)
+	}
+	if runtime.GOOS != "windows" {
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if perm := fi.Mode().Perm(); perm != 0600 {
+			t.Errorf("Store has permissions %v, want 0600", perm)
+		}
+	}
+
+	if err := s.Delete("a"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	all, err := s.All()
+	if err != nil {
+		t.Fatalf("All failed: %v", err)
+	}
+	if len(all) != 1 || all[0] != (OwnedPostParams{ID: "b", Token: "token-b"}) {
+		t.Errorf("All = %+v, want only b", all)
+	}
+
+	t.Run("shared file", func(t *testing.T) {
+		// Separate stores on the same file act like separate processes
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				id := fmt.Sprintf("post%d", i)
+				if err := NewFileTokenStore(path).Put(id, "token"); err != nil {
+					t.Errorf("Put %s failed: %v", id, err)
+				}
+			}(i)
+		}
+		wg.Wait()
+		all, err := s.All()
+		if err != nil {
+			t.Fatalf("All failed: %v", err)
+		}
+		if len(all) != 21 {
+			t.Errorf("Got %d tokens, want 21; concurrent writes were lost", len(all))
+		}
+	})
+}
+
+func TestClientTokenStore(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	dir, err := ioutil.TempDir("", "writeas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s := NewFileTokenStore(filepath.Join(dir, "tokens.json"))
+	c := NewClientWith(Config{URL: srv.APIURL(), TokenStore: s})
+
+	p, err := c.CreatePost(&PostParams{Content: "Anonymous post."})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if tok, _ := s.Get(p.ID); tok == "" || tok != p.Token {
+		t.Fatalf("Stored token %q, want %q", tok, p.Token)
+	}
+
+	if _, err := c.UpdatePost(p.ID, "", &PostParams{Content: "Updated."}); err != nil {
+		t.Errorf("Update with stored token failed: %v", err)
+	}
+	if err := c.DeletePost(p.ID, ""); err != nil {
+		t.Fatalf("Delete with stored token failed: %v", err)
+	}
+	if tok, _ := s.Get(p.ID); tok != "" {
+		t.Errorf("Token still stored after delete: %q", tok)
+	}
+
+	t.Run("without store", func(t *testing.T) {
+		p, err := c.CreatePost(&PostParams{Content: "Another."})
+		if err != nil {
+			t.Fatalf("Create failed: %v", err)
+		}
+		nc := NewClientWith(Config{URL: srv.APIURL()})
+		if err := nc.DeletePost(p.ID, ""); err == nil {
+			t.Errorf("Expected error deleting without a token")
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package writeas
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"time"
@@ -40,6 +41,11 @@ type (
 // GetMe retrieves the authenticated User's information.
 // See: https://developers.write.as/docs/api/#retrieve-authenticated-user
 func (c *Client) GetMe(verbose bool) (*User, error) {
+	return c.GetMeContext(context.Background(), verbose)
+}
+
+// GetMeContext is like GetMe, but uses ctx for the request.
+func (c *Client) GetMeContext(ctx context.Context, verbose bool) (*User, error) {
 	if c.Token() == "" {
 		return nil, fmt.Errorf("Unable to get user; no access token given.")
 	}
@@ -48,17 +54,20 @@ func (c *Client) GetMe(verbose bool) (*User, error) {
 	if verbose {
 		params = "?verbose=true"
 	}
-	env, err := c.get("/me"+params, nil)
+	u := &User{}
+	endpoint := "/me"
+	env, err := c.get(ctx, endpoint+params, u)
 	if err != nil {
 		return nil, err
 	}
 
 	status := env.Code
 	if status == http.StatusUnauthorized {
-		return nil, fmt.Errorf("invalid or expired token")
+		return nil, newAPIError("GET", endpoint, env, "invalid or expired token")
+	} else if status != http.StatusOK {
+		return nil, newAPIError("GET", endpoint, env, fmt.Sprintf("Problem getting user: %d. %s", status, env.ErrorMessage))
 	}
 
-	var u *User
 	var ok bool
 	if u, ok = env.Data.(*User); !ok {
 		return nil, fmt.Errorf("Wrong data returned from API.")
@@ -3,10 +3,12 @@ package writeas
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/writeas/impart"
@@ -24,17 +26,35 @@ const (
 
 // Client is used to interact with the Write.as API. It can be used to make
 // authenticated or unauthenticated calls.
+//
+// A Client is safe for concurrent use by multiple goroutines, including
+// while its settings are changed. To make requests as different users
+// concurrently, derive a Client for each one with WithToken.
 type Client struct {
 	baseURL string
 
+	// mu guards all fields below.
+	mu sync.RWMutex
+
 	// Access token for the user making requests.
 	token string
 	// Application-level API key.
 	apiKey string
 	// Client making requests to the API
 	client *http.Client
-
-	// UserAgent overrides the default User-Agent header
+	// Policy for retrying failed requests
+	retry *RetryPolicy
+	// Limiter gating every request, possibly shared with other Clients
+	limiter *RateLimiter
+	// Middleware wrapping every request
+	middleware []Middleware
+	// Store for the tokens of anonymous posts
+	tokens TokenStore
+	// Detector filling in the language of posts
+	langs *LanguageDetector
+
+	// UserAgent overrides the default User-Agent header. It shouldn't be
+	// assigned once the Client is in use; call SetUserAgent instead.
 	UserAgent string
 }
 
@@ -74,10 +94,37 @@ type Config struct {
 	// proxy.
 	TorPort int
 
+	// If specified, requests will be made with this http.Client instead of
+	// the default one, and TorPort is ignored. The same can be done later
+	// with SetClient.
+	HTTPClient *http.Client
+
 	// If specified, requests will be authenticated using this user token.
 	// This may be provided after making a few anonymous requests with
 	// SetToken.
 	Token string
+
+	// If specified, requests that fail because of transient errors will be
+	// retried according to this policy.
+	Retry *RetryPolicy
+
+	// If specified, every request (including retries) will wait for this
+	// RateLimiter before being sent. It may be shared between Clients.
+	RateLimiter *RateLimiter
+
+	// If specified, every request will pass through these Middleware, in
+	// order. More can be added later with Use.
+	Middleware []Middleware
+
+	// If specified, the tokens of anonymous posts created with this client
+	// will be recorded here, and looked up when updating or deleting a post
+	// without a token. The same can be done later with SetTokenStore.
+	TokenStore TokenStore
+
+	// If specified, the language and direction of posts created or updated
+	// without them will be guessed from their content. The same can be
+	// done later with SetLanguageDetector.
+	LanguageDetector *LanguageDetector
 }
 
 // NewClientWith builds a new API client with the provided configuration.
@@ -86,8 +133,11 @@ func NewClientWith(c Config) *Client {
 		c.URL = apiURL
 	}
 
-	httpClient := &http.Client{Timeout: defaultHTTPTimeout}
-	if c.TorPort > 0 {
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+	if c.HTTPClient == nil && c.TorPort > 0 {
 		dialSocksProxy := socks.DialSocksProxy(socks.SOCKS5, fmt.Sprintf("127.0.0.1:%d", c.TorPort))
 		httpClient.Transport = &http.Transport{Dial: dialSocksProxy}
 	}
@@ -96,27 +146,128 @@ func NewClientWith(c Config) *Client {
 		client:  httpClient,
 		baseURL: c.URL,
 		token:   c.Token,
+		retry:   c.Retry,
+		limiter: c.RateLimiter,
+		tokens:  c.TokenStore,
+		langs:   c.LanguageDetector,
+
+		middleware: append([]Middleware(nil), c.Middleware...),
+	}
+}
+
+// WithToken returns a copy of the Client that makes requests with the given
+// user token. The copy shares the original's http.Client, RetryPolicy,
+// RateLimiter, Middleware and TokenStore, so it's cheap to create one per request, e.g.
+// for each user of a web application sharing a single Client. Later changes
+// to either Client's settings don't affect the other.
+func (c *Client) WithToken(token string) *Client {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return &Client{
+		baseURL:    c.baseURL,
+		token:      token,
+		apiKey:     c.apiKey,
+		client:     c.client,
+		retry:      c.retry,
+		limiter:    c.limiter,
+		middleware: c.middleware[:len(c.middleware):len(c.middleware)],
+		tokens:     c.tokens,
+		langs:      c.langs,
+		UserAgent:  c.UserAgent,
 	}
 }
 
 // SetToken sets the user token for all future Client requests. Setting this to
 // an empty string will change back to unauthenticated requests.
 func (c *Client) SetToken(token string) {
+	c.mu.Lock()
 	c.token = token
+	c.mu.Unlock()
 }
 
 // SetApplicationKey sets an application-level API key for all Client requests.
 func (c *Client) SetApplicationKey(key string) {
+	c.mu.Lock()
 	c.apiKey = key
+	c.mu.Unlock()
 }
 
 // SetClient sets a custom http.Client to use instead of the default.
 func (c *Client) SetClient(cl *http.Client) {
+	c.mu.Lock()
 	c.client = cl
+	c.mu.Unlock()
+}
+
+// SetUserAgent sets the User-Agent header sent with all future Client
+// requests. Setting this to an empty string will change back to the default.
+func (c *Client) SetUserAgent(ua string) {
+	c.mu.Lock()
+	c.UserAgent = ua
+	c.mu.Unlock()
+}
+
+// SetRetryPolicy sets the policy for retrying requests that fail because of
+// transient errors. Passing nil disables retries.
+func (c *Client) SetRetryPolicy(p *RetryPolicy) {
+	c.mu.Lock()
+	c.retry = p
+	c.mu.Unlock()
+}
+
+// SetRateLimiter sets a RateLimiter that every request will wait for before
+// being sent. Passing nil removes any limit.
+func (c *Client) SetRateLimiter(l *RateLimiter) {
+	c.mu.Lock()
+	c.limiter = l
+	c.mu.Unlock()
+}
+
+// SetTokenStore sets a TokenStore for recording the tokens of anonymous
+// posts, and looking them up when updating or deleting a post without a
+// token. Passing nil stops using one.
+func (c *Client) SetTokenStore(s TokenStore) {
+	c.mu.Lock()
+	c.tokens = s
+	c.mu.Unlock()
+}
+
+func (c *Client) tokenStore() TokenStore {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.tokens
+}
+
+// SetLanguageDetector sets a LanguageDetector for guessing the language and
+// direction of posts created or updated without them. Passing nil stops
+// using one.
+func (c *Client) SetLanguageDetector(d *LanguageDetector) {
+	c.mu.Lock()
+	c.langs = d
+	c.mu.Unlock()
+}
+
+// withDetectedLanguage returns sp, or a copy of it with its language and
+// direction filled in by the Client's LanguageDetector.
+func (c *Client) withDetectedLanguage(sp *PostParams) *PostParams {
+	c.mu.RLock()
+	d := c.langs
+	c.mu.RUnlock()
+	if d == nil || sp.SkipLanguageDetection || sp.Title == "" && sp.Content == "" {
+		return sp
+	}
+	detected := *sp
+	if !d.Apply(&detected) {
+		return sp
+	}
+	return &detected
 }
 
 // Token returns the user token currently set to the Client.
 func (c *Client) Token() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.token
 }
 
@@ -125,29 +276,29 @@ func (c *Client) BaseURL() string {
 	return c.baseURL
 }
 
-func (c *Client) get(path string, r interface{}) (*impart.Envelope, error) {
+func (c *Client) get(ctx context.Context, path string, r interface{}) (*impart.Envelope, error) {
 	method := "GET"
 	if method != "GET" && method != "HEAD" {
 		return nil, fmt.Errorf("Method %s not currently supported by library (only HEAD and GET).\n", method)
 	}
 
-	return c.request(method, path, nil, r)
+	return c.request(ctx, method, path, nil, r)
 }
 
-func (c *Client) post(path string, data, r interface{}) (*impart.Envelope, error) {
+func (c *Client) post(ctx context.Context, path string, data, r interface{}) (*impart.Envelope, error) {
 	b := new(bytes.Buffer)
 	json.NewEncoder(b).Encode(data)
-	return c.request("POST", path, b, r)
+	return c.request(ctx, "POST", path, b, r)
 }
 
-func (c *Client) put(path string, data, r interface{}) (*impart.Envelope, error) {
+func (c *Client) put(ctx context.Context, path string, data, r interface{}) (*impart.Envelope, error) {
 	b := new(bytes.Buffer)
 	json.NewEncoder(b).Encode(data)
-	return c.request("PUT", path, b, r)
+	return c.request(ctx, "PUT", path, b, r)
 }
 
-func (c *Client) delete(path string, data map[string]string) (*impart.Envelope, error) {
-	r, err := c.buildRequest("DELETE", path, nil)
+func (c *Client) delete(ctx context.Context, path string, data map[string]string) (*impart.Envelope, error) {
+	r, err := c.buildRequest(ctx, "DELETE", path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -161,8 +312,8 @@ func (c *Client) delete(path string, data map[string]string) (*impart.Envelope,
 	return c.doRequest(r, nil)
 }
 
-func (c *Client) request(method, path string, data io.Reader, result interface{}) (*impart.Envelope, error) {
-	r, err := c.buildRequest(method, path, data)
+func (c *Client) request(ctx context.Context, method, path string, data io.Reader, result interface{}) (*impart.Envelope, error) {
+	r, err := c.buildRequest(ctx, method, path, data)
 	if err != nil {
 		return nil, err
 	}
@@ -170,9 +321,9 @@ func (c *Client) request(method, path string, data io.Reader, result interface{}
 	return c.doRequest(r, result)
 }
 
-func (c *Client) buildRequest(method, path string, data io.Reader) (*http.Request, error) {
+func (c *Client) buildRequest(ctx context.Context, method, path string, data io.Reader) (*http.Request, error) {
 	url := fmt.Sprintf("%s%s", c.baseURL, path)
-	r, err := http.NewRequest(method, url, data)
+	r, err := http.NewRequestWithContext(ctx, method, url, data)
 	if err != nil {
 		return nil, fmt.Errorf("Create request: %v", err)
 	}
@@ -182,8 +333,13 @@ func (c *Client) buildRequest(method, path string, data io.Reader) (*http.Reques
 }
 
 func (c *Client) doRequest(r *http.Request, result interface{}) (*impart.Envelope, error) {
-	resp, err := c.client.Do(r)
+	resp, err := c.send(r)
 	if err != nil {
+		// Surface cancellation and deadlines as-is, so callers can compare
+		// against context.Canceled and context.DeadlineExceeded.
+		if ctxErr := r.Context().Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
 		return nil, fmt.Errorf("Request: %v", err)
 	}
 	defer resp.Body.Close()
@@ -196,6 +352,9 @@ func (c *Client) doRequest(r *http.Request, result interface{}) (*impart.Envelop
 
 		err = json.NewDecoder(resp.Body).Decode(&env)
 		if err != nil {
+			if ctxErr := r.Context().Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
 			return nil, err
 		}
 	}
@@ -204,6 +363,9 @@ func (c *Client) doRequest(r *http.Request, result interface{}) (*impart.Envelop
 }
 
 func (c *Client) prepareRequest(r *http.Request) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	ua := c.UserAgent
 	if ua == "" {
 		ua = "go-writeas v" + Version
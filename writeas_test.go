@@ -0,0 +1,195 @@
+package writeas
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/writeas/go-writeas/v2/writeastest"
+)
+
+// newTestServer starts a fake Write.as server, seeded with a "demo" user
+// (password "demo") who owns a "tester" collection with a few posts on it.
+func newTestServer() *writeastest.Server {
+	srv := writeastest.NewServer()
+	srv.AddUser("demo", "demo")
+	srv.AddCollection("demo", "tester", "Tester")
+	srv.AddCollectionPost("tester", "First post", "This is the first post.")
+	srv.AddCollectionPost("tester", "Second post", "This is the second post. #testing")
+	return srv
+}
+
+// newSlowServer returns a test server that doesn't respond until either the
+// client goes away or the given delay passes.
+func newSlowServer(delay time.Duration) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(ioutil.Discard, r.Body)
+		select {
+		case <-r.Context().Done():
+		case <-time.After(delay):
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"code":200,"data":{"id":"abc","body":"Hi"}}`))
+		}
+	}))
+}
+
+func TestContextCancel(t *testing.T) {
+	srv := newSlowServer(5 * time.Second)
+	defer srv.Close()
+	c := NewClientWith(Config{URL: srv.URL})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	_, err := c.GetPostContext(ctx, "abc")
+	if err != context.Canceled {
+		t.Fatalf("Expected context.Canceled, got: %v", err)
+	}
+	if time.Since(start) > 2*time.Second {
+		t.Errorf("Request wasn't canceled promptly")
+	}
+}
+
+func TestContextDeadline(t *testing.T) {
+	srv := newSlowServer(5 * time.Second)
+	defer srv.Close()
+	c := NewClientWith(Config{URL: srv.URL})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := c.CreatePostContext(ctx, &PostParams{Content: "Hi"})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Expected context.DeadlineExceeded, got: %v", err)
+	}
+}
+
+func TestContextComplete(t *testing.T) {
+	srv := newSlowServer(0)
+	defer srv.Close()
+	c := NewClientWith(Config{URL: srv.URL})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p, err := c.GetPostContext(ctx, "abc")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if p.Content != "Hi" {
+		t.Errorf("Unexpected post: %+v", p)
+	}
+}
+
+// newAuthEchoServer returns a test server that responds with a post whose
+// body is the Authorization header of the request.
+func newAuthEchoServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(ioutil.Discard, r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/auth/login":
+			w.Write([]byte(`{"code":200,"data":{"access_token":"new-token"}}`))
+		case r.URL.Path == "/auth/me":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			fmt.Fprintf(w, `{"code":200,"data":{"id":"abc","body":%q}}`, r.Header.Get("Authorization"))
+		}
+	}))
+}
+
+func TestWithToken(t *testing.T) {
+	srv := newAuthEchoServer()
+	defer srv.Close()
+
+	var reqs int
+	c := NewClientWith(Config{URL: srv.URL, Token: "shared"})
+	c.Use(func(next SendFunc) SendFunc {
+		return func(r *http.Request) (*http.Response, error) {
+			reqs++
+			return next(r)
+		}
+	})
+
+	dc := c.WithToken("derived")
+	p, err := dc.GetPost("abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Content != "Token derived" {
+		t.Errorf("Derived client sent Authorization %q", p.Content)
+	}
+	if reqs != 1 {
+		t.Errorf("Derived client didn't use the original's middleware")
+	}
+
+	// Logging in on the derived client leaves the original alone
+	if _, err := dc.LogIn("user", "pass"); err != nil {
+		t.Fatal(err)
+	}
+	if dc.Token() != "new-token" || c.Token() != "shared" {
+		t.Errorf("Got tokens %q and %q, want %q and %q", dc.Token(), c.Token(), "new-token", "shared")
+	}
+
+	// Middleware added to the derived client doesn't affect the original
+	dc.Use(HeaderMiddleware(http.Header{"X-Test": {"1"}}))
+	c.mu.RLock()
+	n := len(c.middleware)
+	c.mu.RUnlock()
+	if n != 1 {
+		t.Errorf("Original client has %d middleware, want 1", n)
+	}
+}
+
+func TestClientConcurrentUse(t *testing.T) {
+	srv := newAuthEchoServer()
+	defer srv.Close()
+	c := NewClientWith(Config{URL: srv.URL})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(4)
+		go func(i int) {
+			defer wg.Done()
+			c.SetToken(fmt.Sprintf("token-%d", i))
+			c.SetApplicationKey("key")
+			c.SetUserAgent("test")
+			c.SetRetryPolicy(&RetryPolicy{MaxAttempts: 2})
+			c.SetRateLimiter(NewRateLimiter(1000, 10))
+			c.SetClient(&http.Client{Timeout: 5 * time.Second})
+		}(i)
+		go func() {
+			defer wg.Done()
+			if _, err := c.GetPost("abc"); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			c.Use(TimingMiddleware(func(*http.Request, int, time.Duration) {}))
+			if _, err := c.WithToken("derived").GetPost("abc"); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := c.LogIn("user", "pass"); err != nil {
+				t.Error(err)
+			}
+			if err := c.LogOut(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,74 @@
+package writeastest
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	strongReg = regexp.MustCompile(`\*\*(.+?)\*\*|__(.+?)__`)
+	emReg     = regexp.MustCompile(`\*(.+?)\*|_(.+?)_`)
+	tagReg    = regexp.MustCompile(`(?:^|\s)#([\pL\pN_]*\pL[\pL\pN_]*)`)
+)
+
+// renderMarkdown is a minimal Markdown renderer, supporting only paragraphs
+// and emphasis.
+func renderMarkdown(body, collectionURL string) string {
+	out := ""
+	for _, para := range strings.Split(strings.Replace(body, "\r\n", "\n", -1), "\n\n") {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+		para = html.EscapeString(para)
+		para = strongReg.ReplaceAllString(para, "<strong>$1$2</strong>")
+		para = emReg.ReplaceAllString(para, "<em>$1$2</em>")
+		out += "<p>" + para + "</p>\n"
+	}
+	return out
+}
+
+// extractTags returns the hashtags in the given post body.
+func extractTags(body string) []string {
+	tags := []string{}
+	seen := map[string]bool{}
+	for _, m := range tagReg.FindAllStringSubmatch(body, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			tags = append(tags, m[1])
+		}
+	}
+	return tags
+}
+
+// slugify returns a URL-friendly slug for the given title, or the first line
+// of the body if there's no title.
+func slugify(title, body string) string {
+	s := title
+	if s == "" {
+		s = strings.SplitN(strings.TrimSpace(body), "\n", 2)[0]
+		s = strings.TrimLeft(s, "# ")
+	}
+
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	slug := strings.Trim(b.String(), "-")
+	if r := []rune(slug); len(r) > 60 {
+		slug = strings.Trim(string(r[:60]), "-")
+	}
+	if slug == "" {
+		slug = "post"
+	}
+	return slug
+}
@@ -0,0 +1,336 @@
+package writeastest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+)
+
+// Match is a set of flags controlling which parts of a request must match a
+// recorded request for its response to be replayed.
+type Match int
+
+const (
+	MatchMethod Match = 1 << iota
+	MatchPath
+	MatchQuery
+	MatchBody
+
+	// MatchStrict requires every part of the request to match.
+	MatchStrict = MatchMethod | MatchPath | MatchQuery | MatchBody
+)
+
+// redacted replaces any credentials in recordings.
+const redacted = "REDACTED"
+
+// scrubbedFields are the JSON fields that are redacted from request and
+// response bodies.
+var scrubbedFields = map[string]bool{
+	"access_token": true,
+	"token":        true,
+	"pass":         true,
+	"password":     true,
+}
+
+type (
+	// Interaction is a single recorded request and the response to it.
+	Interaction struct {
+		Request  RecordedRequest  `json:"request"`
+		Response RecordedResponse `json:"response"`
+	}
+
+	// RecordedRequest is a request made through a Recorder, with any
+	// credentials scrubbed.
+	RecordedRequest struct {
+		Method string `json:"method"`
+		Path   string `json:"path"`
+		Query  string `json:"query,omitempty"`
+		Body   string `json:"body,omitempty"`
+	}
+
+	// RecordedResponse is a response received through a Recorder, with any
+	// credentials scrubbed.
+	RecordedResponse struct {
+		StatusCode int         `json:"status_code"`
+		Header     http.Header `json:"header,omitempty"`
+		Body       string      `json:"body,omitempty"`
+	}
+)
+
+// Recorder is an http.RoundTripper that either records requests made through
+// it to a golden file, or replays responses from one, so tests can pin the
+// exact behavior of a Write.as or WriteFreely server. Plug it into a client
+// with writeas.Config.HTTPClient or Client.SetClient:
+//
+//	rec := writeastest.NewRecorder("testdata/posts.json", nil)
+//	c := writeas.NewClientWith(writeas.Config{HTTPClient: rec.Client()})
+//	// ... make requests ...
+//	rec.Save()
+//
+// Access tokens, post tokens and passwords are scrubbed from everything that's
+// recorded.
+type Recorder struct {
+	// Match controls which parts of a request must match a recording in
+	// order to replay it. Defaults to MatchStrict.
+	Match Match
+
+	path      string
+	transport http.RoundTripper
+	replay    bool
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewRecorder creates a Recorder that sends requests with the given
+// transport, or http.DefaultTransport if nil, and records them to be saved
+// to the file at path.
+func NewRecorder(path string, transport http.RoundTripper) *Recorder {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &Recorder{
+		path:      path,
+		transport: transport,
+	}
+}
+
+// NewReplayer creates a Recorder that replays the interactions saved in the
+// file at path, without making any real requests. Requests that don't match
+// a recorded interaction fail.
+func NewReplayer(path string) (*Recorder, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var is []Interaction
+	if err := json.Unmarshal(b, &is); err != nil {
+		return nil, fmt.Errorf("parse %s: %v", path, err)
+	}
+	return &Recorder{
+		path:         path,
+		replay:       true,
+		interactions: is,
+		used:         make([]bool, len(is)),
+	}, nil
+}
+
+// Client returns an http.Client that makes requests through the Recorder.
+func (rec *Recorder) Client() *http.Client {
+	return &http.Client{Transport: rec}
+}
+
+// RoundTrip implements http.RoundTripper. It doesn't modify r, but sends a
+// copy of it with a body that can be read again.
+func (rec *Recorder) RoundTrip(r *http.Request) (*http.Response, error) {
+	body, err := requestBody(r)
+	if err != nil {
+		return nil, err
+	}
+	r = r.Clone(r.Context())
+	if body != nil {
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		r.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+	req := RecordedRequest{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  scrubQuery(r.URL.Query()),
+		Body:   scrubBody(body),
+	}
+
+	if rec.replay {
+		return rec.replayResponse(r, req)
+	}
+
+	resp, err := rec.transport.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	rec.mu.Lock()
+	rec.interactions = append(rec.interactions, Interaction{
+		Request: req,
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     recordedHeader(resp.Header),
+			Body:       scrubBody(respBody),
+		},
+	})
+	rec.mu.Unlock()
+
+	return resp, nil
+}
+
+// requestBody reads the body of r, from a new copy of it if r.GetBody is set,
+// and closes r.Body, as a RoundTripper must.
+func requestBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+	defer r.Body.Close()
+	src := r.Body
+	if r.GetBody != nil {
+		b, err := r.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer b.Close()
+		src = b
+	}
+	return ioutil.ReadAll(src)
+}
+
+// replayResponse responds with the first unused interaction that matches the
+// given request.
+func (rec *Recorder) replayResponse(r *http.Request, req RecordedRequest) (*http.Response, error) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	for i, in := range rec.interactions {
+		if rec.used[i] || !rec.matches(req, in.Request) {
+			continue
+		}
+		rec.used[i] = true
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", in.Response.StatusCode, http.StatusText(in.Response.StatusCode)),
+			StatusCode:    in.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        in.Response.Header.Clone(),
+			Body:          ioutil.NopCloser(bytes.NewBufferString(in.Response.Body)),
+			ContentLength: int64(len(in.Response.Body)),
+			Request:       r,
+		}, nil
+	}
+	return nil, fmt.Errorf("no recorded response in %s for %s %s", rec.path, req.Method, r.URL.Path)
+}
+
+func (rec *Recorder) matches(req, recorded RecordedRequest) bool {
+	m := rec.Match
+	if m == 0 {
+		m = MatchStrict
+	}
+	if m&MatchMethod != 0 && req.Method != recorded.Method {
+		return false
+	}
+	if m&MatchPath != 0 && req.Path != recorded.Path {
+		return false
+	}
+	if m&MatchQuery != 0 && req.Query != recorded.Query {
+		return false
+	}
+	if m&MatchBody != 0 && req.Body != recorded.Body {
+		return false
+	}
+	return true
+}
+
+// Interactions returns all interactions recorded or loaded so far.
+func (rec *Recorder) Interactions() []Interaction {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	return append([]Interaction(nil), rec.interactions...)
+}
+
+// Unused returns the loaded interactions that haven't been replayed. If the
+// code under test made all the same requests as when it was recorded, this
+// will be empty.
+func (rec *Recorder) Unused() []Interaction {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	var is []Interaction
+	for i, in := range rec.interactions {
+		if rec.replay && !rec.used[i] {
+			is = append(is, in)
+		}
+	}
+	return is
+}
+
+// Save writes all recorded interactions to the Recorder's file, replacing
+// it. It has no effect when replaying.
+func (rec *Recorder) Save() error {
+	if rec.replay {
+		return nil
+	}
+	rec.mu.Lock()
+	b, err := json.MarshalIndent(rec.interactions, "", "  ")
+	rec.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(rec.path, append(b, '\n'), os.FileMode(0644))
+}
+
+// recordedHeader returns the response headers worth recording, leaving out
+// ones that change on every request or won't apply once the body is scrubbed.
+func recordedHeader(h http.Header) http.Header {
+	out := http.Header{}
+	for k, v := range h {
+		if k == "Date" || k == "Set-Cookie" || k == "Content-Length" {
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// scrubQuery returns the encoded query with any post token redacted.
+func scrubQuery(q url.Values) string {
+	if q.Get("token") != "" {
+		q.Set("token", redacted)
+	}
+	return q.Encode()
+}
+
+// scrubBody redacts credentials from the given JSON body, also normalizing it
+// so that bodies can be compared regardless of formatting. Non-JSON bodies
+// are returned as-is.
+func scrubBody(b []byte) string {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return string(b)
+	}
+	out, err := json.Marshal(scrubJSON(v))
+	if err != nil {
+		return string(b)
+	}
+	return string(out)
+}
+
+func scrubJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			if s, ok := val.(string); ok && s != "" && scrubbedFields[k] {
+				v[k] = redacted
+			} else {
+				v[k] = scrubJSON(val)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = scrubJSON(v[i])
+		}
+	}
+	return v
+}
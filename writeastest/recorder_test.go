@@ -0,0 +1,151 @@
+package writeastest_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/writeas/go-writeas/v2"
+	"github.com/writeas/go-writeas/v2/writeastest"
+)
+
+func TestRecordReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "writeastest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	golden := filepath.Join(dir, "posts.json")
+
+	// Record a session against a fake server
+	srv := writeastest.NewServer()
+	srv.AddUser("demo", "hunter2")
+	rec := writeastest.NewRecorder(golden, nil)
+	c := writeas.NewClientWith(writeas.Config{URL: srv.APIURL(), HTTPClient: rec.Client()})
+
+	if _, err := c.LogIn("demo", "hunter2"); err != nil {
+		t.Fatal(err)
+	}
+	p, err := c.CreatePost(&writeas.PostParams{Title: "Recorded", Content: "This was recorded."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetPost(p.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+
+	b, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"hunter2", c.Token()} {
+		if strings.Contains(string(b), secret) {
+			t.Errorf("Recording contains secret %q", secret)
+		}
+	}
+
+	// Replay the same session without the server
+	rep, err := writeastest.NewReplayer(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c = writeas.NewClientWith(writeas.Config{URL: srv.APIURL(), HTTPClient: rep.Client()})
+	if _, err := c.LogIn("demo", "another password"); err != nil {
+		t.Fatalf("Scrubbed password should still match: %v", err)
+	}
+	rp, err := c.CreatePost(&writeas.PostParams{Title: "Recorded", Content: "This was recorded."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rp.ID != p.ID || rp.Content != p.Content {
+		t.Errorf("Replayed post %+v doesn't match recorded %+v", rp, p)
+	}
+	if n := len(rep.Unused()); n != 1 {
+		t.Errorf("Got %d unused interactions, want 1", n)
+	}
+	if _, err := c.GetPost(p.ID); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(rep.Unused()); n != 0 {
+		t.Errorf("Got %d unused interactions, want 0", n)
+	}
+
+	// Requests that weren't recorded fail
+	if _, err := c.GetPost(p.ID); err == nil {
+		t.Errorf("Expected error replaying an interaction twice")
+	}
+}
+
+func TestReplayStrictMatching(t *testing.T) {
+	dir, err := ioutil.TempDir("", "writeastest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	golden := filepath.Join(dir, "markdown.json")
+
+	srv := writeastest.NewServer()
+	rec := writeastest.NewRecorder(golden, nil)
+	c := writeas.NewClientWith(writeas.Config{URL: srv.APIURL(), HTTPClient: rec.Client()})
+	if _, err := c.Markdown("*Hi*", ""); err != nil {
+		t.Fatal(err)
+	}
+	rec.Save()
+	srv.Close()
+
+	rep, err := writeastest.NewReplayer(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetClient(rep.Client())
+	if _, err := c.Markdown("*Bye*", ""); err == nil {
+		t.Errorf("Expected request with different body not to match")
+	}
+
+	rep.Match = writeastest.MatchMethod | writeastest.MatchPath
+	out, err := c.Markdown("*Bye*", "")
+	if err != nil {
+		t.Fatalf("Expected request to match ignoring body: %v", err)
+	}
+	if out != "<p><em>Hi</em></p>\n" {
+		t.Errorf("Got unexpected replayed response %q", out)
+	}
+}
+
+func TestRecorderLeavesRequestAlone(t *testing.T) {
+	srv := writeastest.NewServer()
+	defer srv.Close()
+	rec := writeastest.NewRecorder("", nil)
+
+	body := strings.NewReader(`{"raw_body":"*Hi*"}`)
+	r, err := http.NewRequest("POST", srv.APIURL()+"/markdown", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	origBody := r.Body
+	resp, err := rec.RoundTrip(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if r.Body != origBody {
+		t.Errorf("RoundTrip replaced the request's body")
+	}
+
+	// The request can still be sent again from GetBody
+	b, err := r.GetBody()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := ioutil.ReadAll(b)
+	if string(got) != `{"raw_body":"*Hi*"}` {
+		t.Errorf("GetBody returned %q", got)
+	}
+}
@@ -0,0 +1,1244 @@
+// Package writeastest provides an in-memory fake of the Write.as API for
+// testing code that uses go-writeas, without making any requests to
+// write.as.
+//
+//	srv := writeastest.NewServer()
+//	defer srv.Close()
+//	srv.AddUser("demo", "demo")
+//
+//	c := writeas.NewClientWith(writeas.Config{URL: srv.APIURL()})
+//	c.LogIn("demo", "demo")
+//
+// The fake implements posts, collections, pinning, claiming, authentication,
+// the authenticated user's information, Markdown rendering and organization
+// contributors, responding with the same envelopes and status codes as the
+// real API.
+package writeastest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PostsPerPage is the number of posts returned per page of collection posts.
+const PostsPerPage = 10
+
+type (
+	user struct {
+		Username string
+		Password string
+		Email    string
+		Created  time.Time
+	}
+
+	post struct {
+		ID         string
+		Slug       string
+		Token      string
+		Font       string
+		Language   *string
+		RTL        *bool
+		Created    time.Time
+		Updated    time.Time
+		Title      string
+		Body       string
+		Views      int64
+		Owner      string
+		Collection string
+		Author     string
+		Pinned     int
+	}
+
+	collection struct {
+		Alias        string
+		Title        string
+		Description  string
+		StyleSheet   string
+		Script       string
+		Format       string
+		Visibility   int
+		Password     string
+		Monetization string
+		Font         string
+		Views        int64
+		Owner        string
+	}
+
+	organization struct {
+		Alias        string
+		Admin        string
+		Contributors []author
+	}
+
+	author struct {
+		Name string `json:"name"`
+		Slug string `json:"slug"`
+	}
+)
+
+// Server is a fake Write.as API server, backed by in-memory data. All of its
+// methods are safe for concurrent use.
+type Server struct {
+	*httptest.Server
+
+	// MaxCollections is the number of collections each user may create, or 0
+	// for no limit.
+	MaxCollections int
+
+	mu          sync.Mutex
+	users       map[string]*user
+	tokens      map[string]string
+	posts       map[string]*post
+	gone        map[string]bool
+	collections map[string]*collection
+	orgs        map[string]*organization
+	markdown    func(body, collectionURL string) string
+}
+
+// NewServer starts a new, empty fake Write.as server. The caller should call
+// Close when finished, to shut it down.
+func NewServer() *Server {
+	s := &Server{
+		users:       map[string]*user{},
+		tokens:      map[string]string{},
+		posts:       map[string]*post{},
+		gone:        map[string]bool{},
+		collections: map[string]*collection{},
+		orgs:        map[string]*organization{},
+		markdown:    renderMarkdown,
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// APIURL returns the base URL of the fake API, for use as a client's
+// configured URL.
+func (s *Server) APIURL() string {
+	return s.URL + "/api"
+}
+
+// AddUser creates a user that can log in with the given username and
+// password.
+func (s *Server) AddUser(username, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users[username] = &user{
+		Username: username,
+		Password: password,
+		Email:    username + "@example.com",
+		Created:  time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+// Token returns a new access token for the given user, as if they had logged
+// in, or an empty string if the user doesn't exist.
+func (s *Server) Token(username string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[username]; !ok {
+		return ""
+	}
+	t := newToken()
+	s.tokens[t] = username
+	return t
+}
+
+// AddCollection creates a collection owned by the given user.
+func (s *Server) AddCollection(username, alias, title string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.collections[alias] = &collection{
+		Alias: alias,
+		Title: title,
+		Owner: username,
+	}
+}
+
+// AddPost publishes an anonymous post, returning its ID and token.
+func (s *Server) AddPost(title, body string) (id, token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := s.newPost("", "", title, body)
+	return p.ID, p.Token
+}
+
+// AddCollectionPost publishes a post on the given collection, returning its
+// ID and slug.
+func (s *Server) AddCollectionPost(alias, title, body string) (id, slug string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	owner := ""
+	if coll, ok := s.collections[alias]; ok {
+		owner = coll.Owner
+	}
+	p := s.newPost(owner, alias, title, body)
+	return p.ID, p.Slug
+}
+
+// AddOrganization creates an organization administered by the given user.
+func (s *Server) AddOrganization(username, alias string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.orgs[alias] = &organization{
+		Alias: alias,
+		Admin: username,
+	}
+}
+
+// SetMarkdownRenderer replaces the function used to render Markdown on the
+// /markdown endpoint. By default, only paragraphs and emphasis are rendered;
+// use markdown.Render to render the way Write.as does.
+func (s *Server) SetMarkdownRenderer(render func(body, collectionURL string) string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.markdown = render
+}
+
+// newPost creates and stores a post. The caller must hold s.mu.
+func (s *Server) newPost(owner, alias, title, body string) *post {
+	now := time.Now().UTC().Truncate(time.Second)
+	p := &post{
+		ID:         newID(16),
+		Font:       "norm",
+		Created:    now,
+		Updated:    now,
+		Title:      title,
+		Body:       body,
+		Owner:      owner,
+		Collection: alias,
+	}
+	if owner == "" {
+		p.Token = newID(32)
+	}
+	if alias != "" {
+		p.Slug = s.uniqueSlug(alias, slugify(title, body), p)
+	}
+	s.posts[p.ID] = p
+	return p
+}
+
+// uniqueSlug returns the given slug, with a suffix if necessary to make it
+// unique within the collection, ignoring the slug of the post being given it.
+// The caller must hold s.mu.
+func (s *Server) uniqueSlug(alias, slug string, self *post) string {
+	taken := map[string]bool{}
+	for _, p := range s.posts {
+		if p.Collection == alias && p != self {
+			taken[p.Slug] = true
+		}
+	}
+	if !taken[slug] {
+		return slug
+	}
+	for i := 2; ; i++ {
+		if s := fmt.Sprintf("%s-%d", slug, i); !taken[s] {
+			return s
+		}
+	}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, "/api/") {
+		writeError(w, http.StatusNotFound, "Page not found.")
+		return
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api"), "/"), "/")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Requests with a bad token are rejected, even on endpoints that don't
+	// require authentication
+	username, authed := s.authenticate(r)
+	if !authed && !match(parts, "auth", "me") {
+		writeError(w, http.StatusUnauthorized, "Invalid access token.")
+		return
+	}
+	req := &request{r: r, w: w, parts: parts, user: username}
+
+	switch {
+	case match(parts, "auth", "login") && r.Method == "POST":
+		s.logIn(req)
+	case match(parts, "auth", "me") && r.Method == "DELETE":
+		s.logOut(req)
+	case match(parts, "me") && r.Method == "GET":
+		s.getMe(req)
+	case match(parts, "me", "posts") && r.Method == "GET":
+		s.getUserPosts(req)
+	case match(parts, "me", "collections") && r.Method == "GET":
+		s.getUserCollections(req)
+	case match(parts, "markdown") && r.Method == "POST":
+		s.renderMarkdown(req)
+	case match(parts, "posts") && r.Method == "POST":
+		s.createPost(req, "")
+	case match(parts, "posts", "claim") && r.Method == "POST":
+		s.claimPosts(req)
+	case match(parts, "posts", "disperse") && r.Method == "POST":
+		s.dispersePosts(req)
+	case match(parts, "posts", "*") && r.Method == "GET":
+		s.getPost(req, parts[1])
+	case match(parts, "posts", "*") && (r.Method == "POST" || r.Method == "PUT"):
+		s.updatePost(req, s.posts[parts[1]])
+	case match(parts, "posts", "*") && r.Method == "DELETE":
+		s.deletePost(req, s.posts[parts[1]])
+	case match(parts, "collections") && r.Method == "POST":
+		s.createCollection(req)
+	case match(parts, "collections", "*") && r.Method == "GET":
+		s.getCollection(req, parts[1])
+	case match(parts, "collections", "*") && r.Method == "POST":
+		s.updateCollection(req, parts[1])
+	case match(parts, "collections", "*") && r.Method == "DELETE":
+		s.deleteCollection(req, parts[1])
+	case match(parts, "collections", "*", "posts") && r.Method == "GET":
+		s.getCollectionPosts(req, parts[1])
+	case match(parts, "collections", "*", "posts") && r.Method == "POST":
+		s.createPost(req, parts[1])
+	case match(parts, "collections", "*", "posts", "*") && r.Method == "GET":
+		s.getCollectionPost(req, parts[1], parts[3])
+	case match(parts, "collections", "*", "posts", "*") && (r.Method == "POST" || r.Method == "PUT"):
+		s.updatePost(req, s.findCollectionPost(parts[1], parts[3]))
+	case match(parts, "collections", "*", "posts", "*") && r.Method == "DELETE":
+		s.deletePost(req, s.findCollectionPost(parts[1], parts[3]))
+	case match(parts, "collections", "*", "collect") && r.Method == "POST":
+		s.collectPosts(req, parts[1])
+	case match(parts, "collections", "*", "pin") && r.Method == "POST":
+		s.pinPosts(req, parts[1], true)
+	case match(parts, "collections", "*", "unpin") && r.Method == "POST":
+		s.pinPosts(req, parts[1], false)
+	case match(parts, "organizations", "*", "contributors") && r.Method == "POST":
+		s.createContributor(req, parts[1])
+	default:
+		writeError(w, http.StatusNotFound, "Not found.")
+	}
+}
+
+// request holds the details of a single API request.
+type request struct {
+	r     *http.Request
+	w     http.ResponseWriter
+	parts []string
+	// user is the authenticated user's username, if any.
+	user string
+}
+
+// decode reads the request's JSON body into v, responding with an error if
+// it's invalid.
+func (req *request) decode(v interface{}) bool {
+	if err := json.NewDecoder(req.r.Body).Decode(v); err != nil {
+		writeError(req.w, http.StatusBadRequest, "Unable to parse request body.")
+		return false
+	}
+	return true
+}
+
+// page returns the requested page of the given posts, defaulting to the
+// first page, or responds with an error if the page number is invalid.
+func (req *request) page(posts []*post) ([]*post, bool) {
+	page := 1
+	if v := req.r.URL.Query().Get("page"); v != "" {
+		var err error
+		if page, err = strconv.Atoi(v); err != nil || page < 1 {
+			writeError(req.w, http.StatusBadRequest, "Invalid page number.")
+			return nil, false
+		}
+	}
+
+	start, end := (page-1)*PostsPerPage, page*PostsPerPage
+	if start > len(posts) {
+		start = len(posts)
+	}
+	if end > len(posts) {
+		end = len(posts)
+	}
+	return posts[start:end], true
+}
+
+// requireUser responds with an error unless the request is authenticated.
+func (req *request) requireUser() bool {
+	if req.user == "" {
+		writeError(req.w, http.StatusUnauthorized, "You need to be logged in to do that.")
+		return false
+	}
+	return true
+}
+
+// authenticate returns the user making the given request, and false if the
+// request has an invalid access token. The caller must hold s.mu.
+func (s *Server) authenticate(r *http.Request) (string, bool) {
+	h := r.Header.Get("Authorization")
+	if h == "" {
+		return "", true
+	}
+	username, ok := s.tokens[strings.TrimPrefix(h, "Token ")]
+	return username, ok
+}
+
+func (s *Server) logIn(req *request) {
+	var in struct {
+		Alias string `json:"alias"`
+		Pass  string `json:"pass"`
+	}
+	if !req.decode(&in) {
+		return
+	}
+	if in.Alias == "" {
+		writeError(req.w, http.StatusBadRequest, "A username is required.")
+		return
+	}
+	if in.Pass == "" {
+		writeError(req.w, http.StatusBadRequest, "A password is required.")
+		return
+	}
+	u, ok := s.users[in.Alias]
+	if !ok {
+		writeError(req.w, http.StatusNotFound, "User not found.")
+		return
+	}
+	if u.Password != in.Pass {
+		writeError(req.w, http.StatusUnauthorized, "Incorrect password.")
+		return
+	}
+
+	t := newToken()
+	s.tokens[t] = u.Username
+	writeData(req.w, http.StatusOK, map[string]interface{}{
+		"access_token": t,
+		"user":         userJSON(u),
+	})
+}
+
+func (s *Server) logOut(req *request) {
+	t := strings.TrimPrefix(req.r.Header.Get("Authorization"), "Token ")
+	if _, ok := s.tokens[t]; !ok {
+		writeError(req.w, http.StatusNotFound, "Access token doesn't exist.")
+		return
+	}
+	delete(s.tokens, t)
+	req.w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) getMe(req *request) {
+	if !req.requireUser() {
+		return
+	}
+	writeData(req.w, http.StatusOK, userJSON(s.users[req.user]))
+}
+
+func (s *Server) getUserPosts(req *request) {
+	if !req.requireUser() {
+		return
+	}
+	posts := []*post{}
+	for _, p := range s.posts {
+		if p.Owner == req.user {
+			posts = append(posts, p)
+		}
+	}
+	sortPosts(posts)
+
+	// Like the real API, all posts are returned at once
+	out := make([]map[string]interface{}, len(posts))
+	for i, p := range posts {
+		out[i] = s.postJSON(p, true)
+	}
+	writeData(req.w, http.StatusOK, out)
+}
+
+func (s *Server) getUserCollections(req *request) {
+	if !req.requireUser() {
+		return
+	}
+	out := []map[string]interface{}{}
+	for _, alias := range s.collectionAliases() {
+		if c := s.collections[alias]; c.Owner == req.user {
+			out = append(out, s.collectionJSON(c))
+		}
+	}
+	writeData(req.w, http.StatusOK, out)
+}
+
+func (s *Server) renderMarkdown(req *request) {
+	var in struct {
+		RawBody       string `json:"raw_body"`
+		CollectionURL string `json:"collection_url"`
+	}
+	if !req.decode(&in) {
+		return
+	}
+	writeData(req.w, http.StatusOK, map[string]string{
+		"body": s.markdown(in.RawBody, in.CollectionURL),
+	})
+}
+
+// postParams are the fields a client can set when creating or updating a
+// post.
+type postParams struct {
+	Token    *string    `json:"token"`
+	Slug     *string    `json:"slug"`
+	Created  *time.Time `json:"created"`
+	Updated  *time.Time `json:"updated"`
+	Title    *string    `json:"title"`
+	Body     *string    `json:"body"`
+	Font     *string    `json:"font"`
+	RTL      *bool      `json:"rtl"`
+	Language *string    `json:"lang"`
+	Author   *string    `json:"author"`
+}
+
+func (s *Server) createPost(req *request, alias string) {
+	if alias != "" {
+		c, ok := s.collections[alias]
+		if !ok {
+			writeError(req.w, http.StatusNotFound, "Collection doesn't exist.")
+			return
+		}
+		if !req.requireUser() {
+			return
+		}
+		if c.Owner != req.user {
+			writeError(req.w, http.StatusForbidden, "You don't have permission to post to this collection.")
+			return
+		}
+	}
+
+	var in postParams
+	if !req.decode(&in) {
+		return
+	}
+	if in.Body == nil || strings.TrimSpace(*in.Body) == "" {
+		writeError(req.w, http.StatusBadRequest, "Post body is empty.")
+		return
+	}
+
+	title := ""
+	if in.Title != nil {
+		title = *in.Title
+	}
+	p := s.newPost(req.user, alias, title, *in.Body)
+	if alias != "" && in.Slug != nil && *in.Slug != "" {
+		p.Slug = s.uniqueSlug(alias, slugify(*in.Slug, ""), p)
+	}
+	applyParams(p, &in)
+	writeData(req.w, http.StatusCreated, s.postJSON(p, true))
+}
+
+// applyParams updates the given post with any fields set in the params,
+// except for its slug.
+func applyParams(p *post, in *postParams) {
+	if in.Created != nil {
+		p.Created = in.Created.UTC()
+	}
+	if in.Updated != nil {
+		p.Updated = in.Updated.UTC()
+	}
+	if in.Title != nil {
+		p.Title = *in.Title
+	}
+	if in.Body != nil && *in.Body != "" {
+		p.Body = *in.Body
+	}
+	if in.Font != nil && *in.Font != "" {
+		p.Font = *in.Font
+	}
+	if in.RTL != nil {
+		p.RTL = in.RTL
+	}
+	if in.Language != nil {
+		p.Language = in.Language
+	}
+	if in.Author != nil {
+		p.Author = *in.Author
+	}
+}
+
+func (s *Server) getPost(req *request, id string) {
+	p, ok := s.posts[id]
+	if !ok {
+		if s.gone[id] {
+			writeError(req.w, http.StatusGone, "Post was unpublished by the author.")
+			return
+		}
+		writeError(req.w, http.StatusNotFound, "Post not found.")
+		return
+	}
+	p.Views++
+	writeData(req.w, http.StatusOK, s.postJSON(p, false))
+}
+
+// canModify responds with an error unless the request is allowed to modify
+// the given post, either with the post's token or as its owner.
+func (s *Server) canModify(req *request, p *post, token string) bool {
+	if p.Owner != "" {
+		if !req.requireUser() {
+			return false
+		}
+		if p.Owner != req.user {
+			writeError(req.w, http.StatusForbidden, "You don't have permission to modify this post.")
+			return false
+		}
+		return true
+	}
+	if token == "" {
+		writeError(req.w, http.StatusBadRequest, "A post token is required.")
+		return false
+	}
+	if token != p.Token {
+		writeError(req.w, http.StatusForbidden, "Invalid post token.")
+		return false
+	}
+	return true
+}
+
+// updatePost updates the given post, or responds with an error if it's nil.
+func (s *Server) updatePost(req *request, p *post) {
+	if p == nil {
+		writeError(req.w, http.StatusNotFound, "Post not found.")
+		return
+	}
+	var in postParams
+	if !req.decode(&in) {
+		return
+	}
+	token := ""
+	if in.Token != nil {
+		token = *in.Token
+	}
+	if !s.canModify(req, p, token) {
+		return
+	}
+
+	applyParams(p, &in)
+	if p.Collection != "" && in.Slug != nil && *in.Slug != "" && *in.Slug != p.Slug {
+		p.Slug = s.uniqueSlug(p.Collection, slugify(*in.Slug, ""), p)
+	}
+	if in.Updated == nil {
+		p.Updated = time.Now().UTC().Truncate(time.Second)
+	}
+	writeData(req.w, http.StatusOK, s.postJSON(p, false))
+}
+
+// deletePost deletes the given post, or responds with an error if it's nil.
+func (s *Server) deletePost(req *request, p *post) {
+	if p == nil {
+		writeError(req.w, http.StatusNotFound, "Post not found.")
+		return
+	}
+	if !s.canModify(req, p, req.r.URL.Query().Get("token")) {
+		return
+	}
+	delete(s.posts, p.ID)
+	s.gone[p.ID] = true
+	req.w.WriteHeader(http.StatusNoContent)
+}
+
+// batchResult is the result of an operation on a single post, as part of a
+// larger batch operation.
+type batchResult struct {
+	ID           string                 `json:"id,omitempty"`
+	Code         int                    `json:"code,omitempty"`
+	ErrorMessage string                 `json:"error_msg,omitempty"`
+	Post         map[string]interface{} `json:"post,omitempty"`
+}
+
+func (s *Server) claimPosts(req *request) {
+	if !req.requireUser() {
+		return
+	}
+	var in []struct {
+		ID    string `json:"id"`
+		Token string `json:"token"`
+	}
+	if !req.decode(&in) {
+		return
+	}
+
+	res := make([]batchResult, len(in))
+	for i, op := range in {
+		res[i].ID = op.ID
+		p, ok := s.posts[op.ID]
+		switch {
+		case !ok:
+			res[i].Code = http.StatusNotFound
+			res[i].ErrorMessage = "Post not found."
+		case p.Owner == req.user:
+			res[i].Code = http.StatusConflict
+			res[i].ErrorMessage = "Post already owned by you."
+		case p.Owner != "" || p.Token != op.Token:
+			res[i].Code = http.StatusForbidden
+			res[i].ErrorMessage = "Invalid post token."
+		default:
+			p.Owner = req.user
+			p.Token = ""
+			res[i].Code = http.StatusOK
+			res[i].Post = s.postJSON(p, false)
+		}
+	}
+	writeData(req.w, http.StatusOK, res)
+}
+
+func (s *Server) collectPosts(req *request, alias string) {
+	if !req.requireUser() {
+		return
+	}
+	c, ok := s.collections[alias]
+	if !ok {
+		writeError(req.w, http.StatusNotFound, "Collection not found.")
+		return
+	}
+	if c.Owner != req.user {
+		writeError(req.w, http.StatusForbidden, "You don't have permission to post to this collection.")
+		return
+	}
+	var in []struct {
+		ID    string `json:"id"`
+		Token string `json:"token"`
+	}
+	if !req.decode(&in) {
+		return
+	}
+
+	res := make([]batchResult, len(in))
+	for i, op := range in {
+		res[i].ID = op.ID
+		p, ok := s.posts[op.ID]
+		switch {
+		case !ok:
+			res[i].Code = http.StatusNotFound
+			res[i].ErrorMessage = "Post not found."
+		case p.Owner == "" && p.Token != op.Token:
+			res[i].Code = http.StatusForbidden
+			res[i].ErrorMessage = "Invalid post token."
+		case p.Owner != "" && p.Owner != req.user:
+			res[i].Code = http.StatusForbidden
+			res[i].ErrorMessage = "Post belongs to someone else."
+		default:
+			if p.Collection != alias {
+				p.Collection = alias
+				p.Slug = s.uniqueSlug(alias, slugify(p.Title, p.Body), p)
+				p.Pinned = 0
+			}
+			p.Owner = req.user
+			p.Token = ""
+			res[i].Code = http.StatusOK
+			res[i].Post = s.postJSON(p, false)
+		}
+	}
+	writeData(req.w, http.StatusOK, res)
+}
+
+func (s *Server) dispersePosts(req *request) {
+	if !req.requireUser() {
+		return
+	}
+	var ids []string
+	if !req.decode(&ids) {
+		return
+	}
+
+	res := make([]batchResult, len(ids))
+	for i, id := range ids {
+		res[i].ID = id
+		p, ok := s.posts[id]
+		switch {
+		case !ok:
+			res[i].Code = http.StatusNotFound
+			res[i].ErrorMessage = "Post not found."
+		case p.Owner != req.user:
+			res[i].Code = http.StatusForbidden
+			res[i].ErrorMessage = "Post belongs to someone else."
+		default:
+			p.Collection = ""
+			p.Slug = ""
+			p.Pinned = 0
+			res[i].Code = http.StatusOK
+		}
+	}
+	writeData(req.w, http.StatusOK, res)
+}
+
+func (s *Server) createCollection(req *request) {
+	if !req.requireUser() {
+		return
+	}
+	var in struct {
+		Alias       string `json:"alias"`
+		Title       string `json:"title"`
+		Description string `json:"description"`
+	}
+	if !req.decode(&in) {
+		return
+	}
+	if in.Alias == "" && in.Title == "" {
+		writeError(req.w, http.StatusBadRequest, "Parameter(s) missing.")
+		return
+	}
+	if in.Alias == "" {
+		in.Alias = slugify(in.Title, "")
+	}
+	if _, ok := s.collections[in.Alias]; ok {
+		writeError(req.w, http.StatusConflict, "Collection name is already taken.")
+		return
+	}
+	if s.MaxCollections > 0 {
+		n := 0
+		for _, c := range s.collections {
+			if c.Owner == req.user {
+				n++
+			}
+		}
+		if n >= s.MaxCollections {
+			writeError(req.w, http.StatusPreconditionFailed, "You've reached the maximum number of collections allowed.")
+			return
+		}
+	}
+
+	c := &collection{
+		Alias:       in.Alias,
+		Title:       in.Title,
+		Description: in.Description,
+		Owner:       req.user,
+	}
+	s.collections[c.Alias] = c
+	writeData(req.w, http.StatusCreated, s.collectionJSON(c))
+}
+
+func (s *Server) getCollection(req *request, alias string) {
+	c, ok := s.collections[alias]
+	if !ok {
+		writeError(req.w, http.StatusNotFound, "Collection not found.")
+		return
+	}
+	c.Views++
+	writeData(req.w, http.StatusOK, s.collectionJSON(c))
+}
+
+// Collection visibility values
+const (
+	visibilityUnlisted = 0
+	visibilityPublic   = 1
+	visibilityPrivate  = 2
+	visibilityPassword = 4
+)
+
+func (s *Server) updateCollection(req *request, alias string) {
+	if !req.requireUser() {
+		return
+	}
+	c, ok := s.collections[alias]
+	if !ok {
+		writeError(req.w, http.StatusNotFound, "Collection not found.")
+		return
+	}
+	if c.Owner != req.user {
+		writeError(req.w, http.StatusForbidden, "You don't have permission to update this collection.")
+		return
+	}
+	var in struct {
+		Title        *string `json:"title"`
+		Description  *string `json:"description"`
+		Visibility   *int    `json:"visibility"`
+		Password     string  `json:"password"`
+		StyleSheet   *string `json:"style_sheet"`
+		Script       *string `json:"script"`
+		Format       *string `json:"format"`
+		Monetization *string `json:"monetization_pointer"`
+		Font         *string `json:"font"`
+	}
+	if !req.decode(&in) {
+		return
+	}
+
+	// Validate everything before changing anything
+	if in.Visibility != nil {
+		switch *in.Visibility {
+		case visibilityUnlisted, visibilityPublic, visibilityPrivate:
+		case visibilityPassword:
+			if in.Password == "" && c.Password == "" {
+				writeError(req.w, http.StatusBadRequest, "A password is required.")
+				return
+			}
+		default:
+			writeError(req.w, http.StatusBadRequest, "Invalid visibility.")
+			return
+		}
+	}
+	if in.Format != nil {
+		switch *in.Format {
+		case "", "blog", "novel", "notebook":
+		default:
+			writeError(req.w, http.StatusBadRequest, "Invalid format.")
+			return
+		}
+	}
+	if in.Font != nil {
+		switch *in.Font {
+		case "", "norm", "sans", "mono", "wrap", "code":
+		default:
+			writeError(req.w, http.StatusBadRequest, "Invalid font.")
+			return
+		}
+	}
+	if in.Monetization != nil && *in.Monetization != "" && !strings.HasPrefix(*in.Monetization, "$") {
+		writeError(req.w, http.StatusBadRequest, "Invalid monetization pointer.")
+		return
+	}
+
+	setString := func(dst *string, v *string) {
+		if v != nil {
+			*dst = *v
+		}
+	}
+	setString(&c.Title, in.Title)
+	setString(&c.Description, in.Description)
+	setString(&c.StyleSheet, in.StyleSheet)
+	setString(&c.Script, in.Script)
+	setString(&c.Format, in.Format)
+	setString(&c.Monetization, in.Monetization)
+	setString(&c.Font, in.Font)
+	if in.Visibility != nil {
+		c.Visibility = *in.Visibility
+	}
+	if in.Password != "" {
+		c.Password = in.Password
+	}
+	writeData(req.w, http.StatusOK, s.collectionJSON(c))
+}
+
+func (s *Server) deleteCollection(req *request, alias string) {
+	if !req.requireUser() {
+		return
+	}
+	c, ok := s.collections[alias]
+	if !ok {
+		writeError(req.w, http.StatusNotFound, "Collection not found.")
+		return
+	}
+	if c.Owner != req.user {
+		writeError(req.w, http.StatusForbidden, "You don't have permission to delete this collection.")
+		return
+	}
+
+	// Posts on the collection become anonymous posts owned by the user
+	for _, p := range s.posts {
+		if p.Collection == alias {
+			p.Collection = ""
+			p.Slug = ""
+			p.Pinned = 0
+		}
+	}
+	delete(s.collections, alias)
+	req.w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) getCollectionPosts(req *request, alias string) {
+	c, ok := s.collections[alias]
+	if !ok {
+		writeError(req.w, http.StatusNotFound, "Collection not found.")
+		return
+	}
+	posts, ok := req.page(s.collectionPosts(alias))
+	if !ok {
+		return
+	}
+	out := make([]map[string]interface{}, 0, len(posts))
+	for _, p := range posts {
+		out = append(out, s.postJSON(p, false))
+	}
+
+	res := s.collectionJSON(c)
+	res["posts"] = out
+	writeData(req.w, http.StatusOK, res)
+}
+
+func (s *Server) getCollectionPost(req *request, alias, slug string) {
+	if _, ok := s.collections[alias]; !ok {
+		writeError(req.w, http.StatusNotFound, "Collection not found.")
+		return
+	}
+	p := s.findCollectionPost(alias, slug)
+	if p == nil {
+		writeError(req.w, http.StatusNotFound, "Post not found.")
+		return
+	}
+	p.Views++
+	writeData(req.w, http.StatusOK, s.postJSON(p, false))
+}
+
+// findCollectionPost returns the post with the given slug on the collection,
+// or nil if there isn't one. The caller must hold s.mu.
+func (s *Server) findCollectionPost(alias, slug string) *post {
+	for _, p := range s.posts {
+		if p.Collection == alias && p.Slug == slug {
+			return p
+		}
+	}
+	return nil
+}
+
+func (s *Server) pinPosts(req *request, alias string, pin bool) {
+	if !req.requireUser() {
+		return
+	}
+	c, ok := s.collections[alias]
+	if !ok {
+		writeError(req.w, http.StatusNotFound, "Collection not found.")
+		return
+	}
+	if c.Owner != req.user {
+		writeError(req.w, http.StatusForbidden, "You don't have permission to modify this collection.")
+		return
+	}
+	var in []struct {
+		ID       string `json:"id"`
+		Position int    `json:"position"`
+	}
+	if !req.decode(&in) {
+		return
+	}
+
+	res := make([]batchResult, len(in))
+	for i, op := range in {
+		res[i].ID = op.ID
+		p, ok := s.posts[op.ID]
+		if !ok || p.Collection != alias {
+			res[i].Code = http.StatusNotFound
+			res[i].ErrorMessage = "Post not found in collection."
+			continue
+		}
+		if pin {
+			p.Pinned = op.Position
+			if p.Pinned <= 0 {
+				p.Pinned = s.nextPinPosition(alias)
+			}
+		} else {
+			p.Pinned = 0
+		}
+		res[i].Code = http.StatusOK
+	}
+	writeData(req.w, http.StatusOK, res)
+}
+
+// nextPinPosition returns the position after the last pinned post in the
+// collection. The caller must hold s.mu.
+func (s *Server) nextPinPosition(alias string) int {
+	max := 0
+	for _, p := range s.posts {
+		if p.Collection == alias && p.Pinned > max {
+			max = p.Pinned
+		}
+	}
+	return max + 1
+}
+
+func (s *Server) createContributor(req *request, alias string) {
+	if !req.requireUser() {
+		return
+	}
+	o, ok := s.orgs[alias]
+	if !ok {
+		writeError(req.w, http.StatusNotFound, "Organization not found.")
+		return
+	}
+	if o.Admin != req.user {
+		writeError(req.w, http.StatusForbidden, "You don't have permission to add contributors.")
+		return
+	}
+	var in author
+	if !req.decode(&in) {
+		return
+	}
+	if in.Name == "" {
+		writeError(req.w, http.StatusBadRequest, "A name is required.")
+		return
+	}
+	if in.Slug == "" {
+		in.Slug = slugify(in.Name, "")
+	}
+	for _, a := range o.Contributors {
+		if a.Slug == in.Slug {
+			writeError(req.w, http.StatusConflict, "Author slug is already taken.")
+			return
+		}
+	}
+	o.Contributors = append(o.Contributors, in)
+	writeData(req.w, http.StatusCreated, in)
+}
+
+// collectionPosts returns all posts on the collection, pinned posts first,
+// then newest first. The caller must hold s.mu.
+func (s *Server) collectionPosts(alias string) []*post {
+	posts := []*post{}
+	for _, p := range s.posts {
+		if p.Collection == alias {
+			posts = append(posts, p)
+		}
+	}
+	sortPosts(posts)
+	return posts
+}
+
+// collectionAliases returns all collection aliases in a stable order. The
+// caller must hold s.mu.
+func (s *Server) collectionAliases() []string {
+	aliases := make([]string, 0, len(s.collections))
+	for a := range s.collections {
+		aliases = append(aliases, a)
+	}
+	sort.Strings(aliases)
+	return aliases
+}
+
+func sortPosts(posts []*post) {
+	sort.Slice(posts, func(i, j int) bool {
+		a, b := posts[i], posts[j]
+		if (a.Pinned > 0) != (b.Pinned > 0) {
+			return a.Pinned > 0
+		}
+		if a.Pinned != b.Pinned {
+			return a.Pinned < b.Pinned
+		}
+		if !a.Created.Equal(b.Created) {
+			return a.Created.After(b.Created)
+		}
+		return a.ID < b.ID
+	})
+}
+
+func userJSON(u *user) map[string]interface{} {
+	return map[string]interface{}{
+		"username": u.Username,
+		"email":    u.Email,
+		"created":  u.Created,
+	}
+}
+
+// postJSON returns the API representation of the given post, including its
+// token only if withToken is true. The caller must hold s.mu.
+func (s *Server) postJSON(p *post, withToken bool) map[string]interface{} {
+	tags := extractTags(p.Body)
+	out := map[string]interface{}{
+		"id":         p.ID,
+		"slug":       p.Slug,
+		"appearance": p.Font,
+		"language":   p.Language,
+		"rtl":        p.RTL,
+		"listed":     true,
+		"type":       "post",
+		"created":    p.Created,
+		"updated":    p.Updated,
+		"title":      p.Title,
+		"body":       p.Body,
+		"views":      p.Views,
+		"tags":       tags,
+		"images":     []string{},
+	}
+	if withToken && p.Token != "" {
+		out["token"] = p.Token
+	}
+	if p.Owner != "" {
+		out["owner"] = p.Owner
+	}
+	if c, ok := s.collections[p.Collection]; ok {
+		out["collection"] = s.collectionJSON(c)
+	}
+	return out
+}
+
+// collectionJSON returns the API representation of the given collection.
+// The caller must hold s.mu.
+func (s *Server) collectionJSON(c *collection) map[string]interface{} {
+	total := 0
+	for _, p := range s.posts {
+		if p.Collection == c.Alias {
+			total++
+		}
+	}
+	out := map[string]interface{}{
+		"alias":       c.Alias,
+		"title":       c.Title,
+		"description": c.Description,
+		"style_sheet": c.StyleSheet,
+		"private":     c.Visibility == visibilityPrivate,
+		"visibility":  c.Visibility,
+		"views":       c.Views,
+		"url":         s.URL + "/" + c.Alias + "/",
+		"total_posts": total,
+	}
+	for k, v := range map[string]string{
+		"script":               c.Script,
+		"format":               c.Format,
+		"monetization_pointer": c.Monetization,
+		"font":                 c.Font,
+	} {
+		if v != "" {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func writeData(w http.ResponseWriter, code int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"code": code,
+		"data": data,
+	})
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"code":      code,
+		"error_msg": msg,
+	})
+}
+
+// match reports whether the given path parts match the pattern, where "*"
+// matches any single part.
+func match(parts []string, pattern ...string) bool {
+	if len(parts) != len(pattern) {
+		return false
+	}
+	for i, p := range pattern {
+		if p != "*" && p != parts[i] {
+			return false
+		}
+	}
+	return true
+}
+
+const idChars = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+func newID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	for i := range b {
+		b[i] = idChars[int(b[i])%len(idChars)]
+	}
+	return string(b)
+}
+
+func newToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	h := hex.EncodeToString(b)
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
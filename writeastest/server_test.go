@@ -0,0 +1,131 @@
+package writeastest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// do makes a request to the fake server, returning the response envelope.
+func do(t *testing.T, s *Server, method, path, token string, body interface{}) (int, map[string]interface{}) {
+	t.Helper()
+	b := new(bytes.Buffer)
+	if body != nil {
+		json.NewEncoder(b).Encode(body)
+	}
+	r, err := http.NewRequest(method, s.APIURL()+path, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		r.Header.Set("Authorization", "Token "+token)
+	}
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	env := map[string]interface{}{}
+	if resp.StatusCode != http.StatusNoContent {
+		if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+			t.Fatalf("%s %s: invalid envelope: %v", method, path, err)
+		}
+		if code, _ := env["code"].(float64); int(code) != resp.StatusCode {
+			t.Errorf("%s %s: envelope code %v doesn't match status %d", method, path, env["code"], resp.StatusCode)
+		}
+	}
+	return resp.StatusCode, env
+}
+
+func TestServerStatusCodes(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.AddUser("demo", "demo")
+	s.AddUser("other", "other")
+	s.AddCollection("demo", "blog", "Blog")
+	s.MaxCollections = 2
+	token := s.Token("demo")
+	otherToken := s.Token("other")
+	id, postToken := s.AddPost("", "Anonymous post")
+	_, slug := s.AddCollectionPost("blog", "Hello World", "Hi")
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   interface{}
+		code   int
+	}{
+		{"log in", "POST", "/auth/login", "", map[string]string{"alias": "demo", "pass": "demo"}, http.StatusOK},
+		{"log in, wrong password", "POST", "/auth/login", "", map[string]string{"alias": "demo", "pass": "nope"}, http.StatusUnauthorized},
+		{"log in, no user", "POST", "/auth/login", "", map[string]string{"alias": "nobody", "pass": "nope"}, http.StatusNotFound},
+		{"log in, missing password", "POST", "/auth/login", "", map[string]string{"alias": "demo"}, http.StatusBadRequest},
+		{"bad token", "GET", "/posts/" + id, "bad-token", nil, http.StatusUnauthorized},
+		{"get me", "GET", "/me", token, nil, http.StatusOK},
+		{"get me, unauthenticated", "GET", "/me", "", nil, http.StatusUnauthorized},
+		{"get post", "GET", "/posts/" + id, "", nil, http.StatusOK},
+		{"get missing post", "GET", "/posts/nope", "", nil, http.StatusNotFound},
+		{"create post, empty", "POST", "/posts", "", map[string]string{"body": ""}, http.StatusBadRequest},
+		{"create post", "POST", "/posts", "", map[string]string{"body": "Hi"}, http.StatusCreated},
+		{"update post, wrong token", "PUT", "/posts/" + id, "", map[string]string{"token": "nope", "body": "Hey"}, http.StatusForbidden},
+		{"update post", "PUT", "/posts/" + id, "", map[string]string{"token": postToken, "body": "Hey"}, http.StatusOK},
+		{"get collection", "GET", "/collections/blog", "", nil, http.StatusOK},
+		{"get missing collection", "GET", "/collections/nope", "", nil, http.StatusNotFound},
+		{"get collection post", "GET", "/collections/blog/posts/" + slug, "", nil, http.StatusOK},
+		{"create collection post, not owner", "POST", "/collections/blog/posts", otherToken, map[string]string{"body": "Hi"}, http.StatusForbidden},
+		{"create collection, taken", "POST", "/collections", token, map[string]string{"alias": "blog"}, http.StatusConflict},
+		{"create collection", "POST", "/collections", token, map[string]string{"alias": "blog2"}, http.StatusCreated},
+		{"create collection, quota", "POST", "/collections", token, map[string]string{"alias": "blog3"}, http.StatusPreconditionFailed},
+		{"delete collection, not owner", "DELETE", "/collections/blog", otherToken, nil, http.StatusForbidden},
+		{"delete collection, unauthenticated", "DELETE", "/collections/blog", "", nil, http.StatusUnauthorized},
+		{"delete post", "DELETE", "/posts/" + id + "?token=" + postToken, "", nil, http.StatusNoContent},
+		{"get deleted post", "GET", "/posts/" + id, "", nil, http.StatusGone},
+		{"log out", "DELETE", "/auth/me", otherToken, nil, http.StatusNoContent},
+		{"log out, invalid token", "DELETE", "/auth/me", otherToken, nil, http.StatusNotFound},
+	}
+	for _, test := range tests {
+		code, env := do(t, s, test.method, test.path, test.token, test.body)
+		if code != test.code {
+			t.Errorf("%s: got status %d, want %d (%v)", test.name, code, test.code, env["error_msg"])
+		}
+		if code >= 400 && env["error_msg"] == "" {
+			t.Errorf("%s: missing error_msg", test.name)
+		}
+	}
+}
+
+func TestServerCollectionPostsPaging(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.AddUser("demo", "demo")
+	s.AddCollection("demo", "blog", "Blog")
+	for i := 0; i < PostsPerPage+3; i++ {
+		s.AddCollectionPost("blog", "", "Same title")
+	}
+
+	_, env := do(t, s, "GET", "/collections/blog/posts?page=2", "", nil)
+	data := env["data"].(map[string]interface{})
+	if n := len(data["posts"].([]interface{})); n != 3 {
+		t.Errorf("Got %d posts on page 2, want 3", n)
+	}
+	if total := data["total_posts"].(float64); int(total) != PostsPerPage+3 {
+		t.Errorf("Got %v total posts, want %d", total, PostsPerPage+3)
+	}
+
+	// Slugs are unique
+	_, env = do(t, s, "GET", "/collections/blog/posts/same-title-2", "", nil)
+	if env["code"].(float64) != http.StatusOK {
+		t.Errorf("Expected suffixed slug to exist: %v", env)
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	in := "This is *formatted* in __Markdown__.\n\nA <b>second</b> paragraph."
+	out := "<p>This is <em>formatted</em> in <strong>Markdown</strong>.</p>\n<p>A &lt;b&gt;second&lt;/b&gt; paragraph.</p>\n"
+	if got := renderMarkdown(in, ""); got != out {
+		t.Errorf("Got %q, want %q", got, out)
+	}
+}